@@ -9,10 +9,16 @@ RUN go mod download
 
 COPY . .
 
-# Build para bot sem a tag client
-RUN go build -tags "!client" -o bot bot.go
+# Build do bot
+RUN go build -o bot ./cmd/bot
 
-# Build para cliente com a tag client  
-RUN go build -tags client -o client main.go
+# Build do cliente interativo
+RUN go build -o client ./cmd/client
+
+# Build do gateway HTTP
+RUN go build -o gateway ./cmd/gateway
+
+# Build do gateway IRC
+RUN go build -o ircgateway ./cmd/ircgateway
 
 CMD ["./client"]
@@ -0,0 +1,284 @@
+package chatsdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+)
+
+const (
+	// Margem no início do intervalo conferido: os timestamps vêm do relógio
+	// de cada cliente, que pode estar atrasado em relação ao nosso
+	backfillMargin = 5 * time.Second
+	// Publicações pedidas por página do histórico
+	backfillPage = 100
+	// Quantas publicações de cada canal são lembradas para a conferência
+	seenWindow = 1000
+)
+
+// seenEntry é uma publicação lembrada por channelGaps.
+type seenEntry struct {
+	key       string
+	timestamp int64
+}
+
+// channelGaps acompanha um canal assinado: até onde ele já foi conferido
+// com o histórico e quantas vezes cada publicação recente foi entregue.
+type channelGaps struct {
+	// Timestamp da última publicação vista quando o canal foi conferido,
+	// ou do início do acompanhamento; a próxima conferência parte dele
+	checked int64
+	// Início do acompanhamento: o que foi publicado antes não é conferido
+	floor int64
+	// Timestamp da publicação mais recente vista
+	last  int64
+	seen  map[string]int
+	order []seenEntry
+	// Publicações entregues pela recuperação que ainda podem chegar ao
+	// vivo (estavam a caminho durante a conferência)
+	recovered map[string]int
+}
+
+func (ch *channelGaps) add(key string, timestamp int64) {
+	ch.seen[key]++
+	ch.order = append(ch.order, seenEntry{key, timestamp})
+	ch.last = max(ch.last, timestamp)
+	if len(ch.order) > seenWindow {
+		// A publicação esquecida fica fora das próximas conferências
+		oldest := ch.order[0]
+		ch.forget(oldest)
+		ch.order = ch.order[1:]
+		ch.checked = max(ch.checked, oldest.timestamp+backfillMargin.Milliseconds()+1)
+	}
+}
+
+func (ch *channelGaps) forget(e seenEntry) {
+	ch.seen[e.key]--
+	if ch.seen[e.key] <= 0 {
+		delete(ch.seen, e.key)
+		delete(ch.recovered, e.key)
+	}
+}
+
+// prune esquece as publicações anteriores ao intervalo da próxima
+// conferência.
+func (ch *channelGaps) prune() {
+	cutoff := ch.checked - backfillMargin.Milliseconds()
+	for len(ch.order) > 0 && ch.order[0].timestamp < cutoff {
+		ch.forget(ch.order[0])
+		ch.order = ch.order[1:]
+	}
+}
+
+// missing retorna as publicações do histórico (em ordem) que não foram
+// entregues, contando repetições: publicações iguais só são descartadas
+// tantas vezes quantas já foram vistas. As retornadas passam a contar como
+// vistas.
+func (ch *channelGaps) missing(history []Publication) []Publication {
+	used := make(map[string]int)
+	var missed []Publication
+	for _, p := range history {
+		key := publicationKey(&p)
+		if used[key] < ch.seen[key] {
+			used[key]++
+			continue
+		}
+		missed = append(missed, p)
+	}
+	for i := range missed {
+		key := publicationKey(&missed[i])
+		ch.add(key, missed[i].Timestamp)
+		ch.recovered[key]++
+	}
+	return missed
+}
+
+// gapTracker lembra as publicações entregues ao vivo em cada canal, para que
+// a recuperação pelo histórico entregue só as que não chegaram. A entrega ao
+// vivo não é filtrada. Só é usado pela goroutine de Listen.
+type gapTracker struct {
+	channels  map[string]*channelGaps
+	lastCheck time.Time
+	// Conferência com o histórico mesmo sem queda (WithGapCheck)
+	checkEvery time.Duration
+}
+
+func newGapTracker(checkEvery time.Duration) *gapTracker {
+	return &gapTracker{
+		channels:   make(map[string]*channelGaps),
+		lastCheck:  time.Now(),
+		checkEvery: checkEvery,
+	}
+}
+
+// publicationKey identifica a publicação pelo meta.id ou, na falta dele,
+// pelo autor, timestamp e texto.
+func publicationKey(p *Publication) string {
+	if p.Meta != nil && p.Meta.ID != "" {
+		return "id:" + p.Meta.ID
+	}
+	return fmt.Sprintf("%s\x00%d\x00%s", p.User, p.Timestamp, p.Message)
+}
+
+// channel retorna o acompanhamento do canal, começando em start se ele
+// ainda não existe.
+func (g *gapTracker) channel(name string, start int64) *channelGaps {
+	ch, ok := g.channels[name]
+	if !ok {
+		ch = &channelGaps{
+			checked:   start,
+			floor:     start,
+			seen:      make(map[string]int),
+			recovered: make(map[string]int),
+		}
+		g.channels[name] = ch
+	}
+	return ch
+}
+
+// observe registra uma publicação que chegou ao vivo e indica se ela deve
+// ser entregue. Só não é entregue a que a recuperação já entregou; repetidas
+// ao vivo são todas entregues. Sem conferências periódicas, a entrega ao
+// vivo conta como conferida.
+func (g *gapTracker) observe(p *Publication) bool {
+	ch := g.channel(p.Channel, p.Timestamp)
+	key := publicationKey(p)
+	if ch.recovered[key] > 0 {
+		ch.recovered[key]--
+		return false
+	}
+
+	ch.add(key, p.Timestamp)
+	if g.checkEvery <= 0 {
+		ch.checked = max(ch.checked, p.Timestamp)
+		ch.prune()
+	}
+	return true
+}
+
+// due indica se está na hora da conferência periódica.
+func (g *gapTracker) due(now time.Time) bool {
+	return g.checkEvery > 0 && now.Sub(g.lastCheck) >= g.checkEvery
+}
+
+// doneContext retorna um contexto cancelado por Close, para as requisições
+// feitas pela goroutine de Listen.
+func (c *Client) doneContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-c.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// backfill confere cada canal assinado com o histórico, a partir da última
+// publicação vista antes da conferência anterior (ou de since, para canais
+// ainda não acompanhados), e entrega ao handler, em ordem de timestamp, as que
+// não tinham chegado. Retorna quantas foram recuperadas e o primeiro erro,
+// inclusive ErrBackfillTruncated.
+func (c *Client) backfill(h Handler, gaps *gapTracker, since time.Time) (int, error) {
+	gaps.lastCheck = time.Now()
+	if c.opts.backfillLimit <= 0 {
+		return 0, nil
+	}
+
+	ctx, cancel := c.doneContext()
+	defer cancel()
+
+	inbox := c.Username()
+	subscribed := make(map[string]bool)
+	var missed []Publication
+	var firstErr error
+	for _, channel := range c.Subscriptions() {
+		if channel == inbox {
+			continue
+		}
+		subscribed[channel] = true
+
+		ch := gaps.channel(channel, since.UnixMilli())
+		start := max(ch.checked-backfillMargin.Milliseconds(), ch.floor)
+		history, err := c.channelHistory(ctx, channel, start)
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("canal '%s': %w", channel, err)
+		}
+		if err != nil && !errors.Is(err, ErrBackfillTruncated) {
+			// Fica para a próxima conferência
+			continue
+		}
+		missed = append(missed, ch.missing(history)...)
+		ch.checked = max(ch.checked, ch.last)
+		ch.prune()
+	}
+
+	// Canais que deixaram de ser assinados não são mais acompanhados
+	for channel := range gaps.channels {
+		if !subscribed[channel] {
+			delete(gaps.channels, channel)
+		}
+	}
+
+	// Publicações de canais diferentes são intercaladas pelo timestamp; o
+	// clock de páginas vindas de réplicas diferentes não é comparável
+	sort.SliceStable(missed, func(i, j int) bool {
+		return missed[i].Timestamp < missed[j].Timestamp
+	})
+
+	for i := range missed {
+		p := &missed[i]
+		p.Recovered = true
+		c.clock.Merge(p.Clock)
+		c.mergeHLC(p)
+		c.deliverPublication(h, *p)
+	}
+	return len(missed), firstErr
+}
+
+// channelHistory busca, página a página, as publicações do canal com
+// timestamp a partir de start, até WithBackfillLimit. Cada página vem da
+// réplica que atender o pedido, então o cursor é o timestamp e o request_id
+// da mais antiga recebida, iguais em todas. Se o limite interrompe a busca,
+// retorna as mais recentes com ErrBackfillTruncated.
+func (c *Client) channelHistory(ctx context.Context, channel string, start int64) ([]Publication, error) {
+	limit := c.opts.backfillLimit
+	q := HistoryQuery{Since: start, Limit: min(backfillPage, limit)}
+	var history []Publication
+	for {
+		response, err := c.History(ctx, channel, q)
+		if err != nil {
+			return nil, err
+		}
+		page := response.Publications
+		if q.Before != 0 {
+			// Itens que não são anteriores ao cursor já estão em history
+			cursor := Header{Timestamp: q.Before, RequestID: q.BeforeID}
+			page = slices.DeleteFunc(page, func(p Publication) bool {
+				return !historyBefore(p.Header, cursor)
+			})
+		}
+		history = append(page, history...)
+		if !response.More || len(page) == 0 {
+			return history, nil
+		}
+		if len(history) >= limit {
+			return history, ErrBackfillTruncated
+		}
+		q = q.NextPage(page[0].Header)
+		q.Limit = min(backfillPage, limit-len(history))
+	}
+}
+
+// historyBefore indica se a vem antes de b na ordem do histórico: por
+// timestamp e, no mesmo milissegundo, por request_id.
+func historyBefore(a, b Header) bool {
+	if a.Timestamp != b.Timestamp {
+		return a.Timestamp < b.Timestamp
+	}
+	return a.RequestID < b.RequestID
+}
@@ -0,0 +1,270 @@
+package chatsdk_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"chat-client/chatsdk"
+	"chat-client/chatsdk/chattest"
+)
+
+// historyOrder ordena publicações como o histórico do servidor: por
+// timestamp e, no mesmo milissegundo, por request_id.
+func historyOrder(publications []chatsdk.Publication) {
+	sort.SliceStable(publications, func(i, j int) bool {
+		a, b := publications[i], publications[j]
+		if a.Timestamp != b.Timestamp {
+			return a.Timestamp < b.Timestamp
+		}
+		return a.RequestID < b.RequestID
+	})
+}
+
+// publishLost publica n mensagens com o proxy mudo, para que só cheguem
+// pelo histórico, como se o socket SUB as descartasse.
+func publishLost(t *testing.T, s *chattest.Server, sender *chatsdk.Client, n int) {
+	t.Helper()
+	s.Mute(true)
+	for i := 0; i < n; i++ {
+		meta := &chatsdk.Meta{ID: fmt.Sprintf("perdida-%d", i)}
+		_, err := sender.PublishMessageWithMeta(context.Background(), "geral", fmt.Sprintf("perdida %d", i), meta)
+		if err != nil {
+			t.Fatalf("publicar: %v", err)
+		}
+	}
+	s.Mute(false)
+}
+
+// recoveredBy soma as publicações recuperadas pelas conferências periódicas
+// a partir do evento start, falhando se algum evento não for de uma.
+func recoveredBy(t *testing.T, received *inbox, start int) int {
+	t.Helper()
+	total := 0
+	for _, event := range received.states(start) {
+		if event.State != chatsdk.StateConnected || event.Err != nil || !event.Since.IsZero() {
+			t.Fatalf("evento da conferência = %+v", event)
+		}
+		total += event.Recovered
+	}
+	return total
+}
+
+// deliveredOnce confere que cada uma das n publicações perdidas foi entregue
+// exatamente uma vez, em ordem de timestamp, depois das ao vivo.
+func deliveredOnce(t *testing.T, received *inbox, live, n int) {
+	t.Helper()
+	received.mu.Lock()
+	defer received.mu.Unlock()
+	if len(received.publications) != live+n {
+		t.Fatalf("%d publicações entregues, esperadas %d", len(received.publications), live+n)
+	}
+	seen := make(map[string]bool)
+	var last int64
+	for _, p := range received.publications[live:] {
+		if p.Meta == nil || seen[p.Meta.ID] {
+			t.Fatalf("publicação repetida ou sem id: %+v", p)
+		}
+		seen[p.Meta.ID] = true
+		if p.Timestamp < last {
+			t.Fatalf("publicação %q fora da ordem de timestamp", p.Message)
+		}
+		last = p.Timestamp
+	}
+}
+
+// TestLiveDuplicatesDelivered garante que publicações iguais que chegam ao
+// vivo são todas entregues.
+func TestLiveDuplicatesDelivered(t *testing.T) {
+	s := newServer(t)
+	client := newClient(t, s)
+	received := &inbox{}
+	client.Subscribe("geral")
+	client.Listen(received.handler())
+	subscribed(t, s, received, "geral")
+
+	for i := 0; i < 2; i++ {
+		p := &chatsdk.Publication{Channel: "geral", User: "bob", Message: "repetida"}
+		p.Timestamp = 1000
+		s.Publish("geral", p)
+	}
+	waitFor(t, "publicações repetidas", func() bool {
+		n, _ := received.counts()
+		return n == 2
+	})
+}
+
+// TestGapCheckRecoversWithoutReconnect perde publicações sem queda da
+// assinatura: a conferência periódica as recupera do histórico, em páginas.
+func TestGapCheckRecoversWithoutReconnect(t *testing.T) {
+	s := newServer(t)
+	s.AddChannel("geral")
+	client := newClient(t, s, chatsdk.WithIdleTimeout(0), chatsdk.WithGapCheck(300*time.Millisecond))
+	received := &inbox{}
+	client.Subscribe("geral")
+	client.Listen(received.handler())
+	subscribed(t, s, received, "geral")
+	start := len(received.states(0))
+
+	sender := newClient(t, s)
+	_, err := sender.Login(context.Background(), "alice")
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	_, err = sender.PublishMessage(context.Background(), "geral", "ao vivo")
+	if err != nil {
+		t.Fatalf("publicar: %v", err)
+	}
+	waitFor(t, "publicação ao vivo", func() bool {
+		n, _ := received.counts()
+		return n == 1
+	})
+
+	const lost = 250
+	publishLost(t, s, sender, lost)
+
+	// A conferência pode acontecer no meio dos envios; somam-se os eventos
+	waitFor(t, "recuperação", func() bool {
+		return recoveredBy(t, received, start) >= lost
+	})
+	if n := recoveredBy(t, received, start); n != lost {
+		t.Fatalf("%d publicações recuperadas, esperadas %d", n, lost)
+	}
+	if _, ok := received.findState(0, chatsdk.StateDisconnected); ok {
+		t.Fatalf("conferência periódica não deveria desconectar")
+	}
+
+	// A publicação vista ao vivo não é entregue de novo
+	time.Sleep(500 * time.Millisecond)
+	deliveredOnce(t, received, 1, lost)
+}
+
+// TestBackfillPagesAcrossReplicas serve cada página do histórico de uma
+// réplica diferente, cada uma com o seu clock, e com vários itens no mesmo
+// milissegundo. Com o clock como cursor as páginas se sobreporiam ou
+// deixariam buracos; com timestamp e request_id, cada publicação perdida é
+// entregue uma vez.
+func TestBackfillPagesAcrossReplicas(t *testing.T) {
+	s := newServer(t)
+	s.AddChannel("geral")
+	client := newClient(t, s, chatsdk.WithIdleTimeout(0), chatsdk.WithGapCheck(300*time.Millisecond))
+	received := &inbox{}
+	client.Subscribe("geral")
+	client.Listen(received.handler())
+	subscribed(t, s, received, "geral")
+	start := len(received.states(0))
+
+	sender := newClient(t, s)
+	_, err := sender.Login(context.Background(), "alice")
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	_, err = sender.PublishMessageWithMeta(context.Background(), "geral", "ao vivo", &chatsdk.Meta{ID: "ao-vivo"})
+	if err != nil {
+		t.Fatalf("publicar: %v", err)
+	}
+	waitFor(t, "publicação ao vivo", func() bool {
+		n, _ := received.counts()
+		return n == 1
+	})
+
+	// As réplicas alternam a cada pedido; a primeira numera os itens a
+	// partir de 1000 e a segunda a partir de 1. Grupos de 7 itens dividem o
+	// mesmo timestamp, para que as páginas de 100 cortem um grupo.
+	var requests atomic.Int32
+	s.Handle(chatsdk.ServiceHistory, func(request chatsdk.Payload) chatsdk.Payload {
+		r := request.(*chatsdk.HistoryRequest)
+		offset := int64(1000)
+		if requests.Add(1)%2 == 0 {
+			offset = 1
+		}
+		all := s.Publications()
+		base := all[0].Timestamp
+		var items []chatsdk.Publication
+		for i, p := range all {
+			p.Timestamp = base + int64(i/7)
+			p.Clock = offset + int64(i)
+			cursor := p.Timestamp < r.Before || (p.Timestamp == r.Before && p.RequestID < r.BeforeID)
+			if p.Timestamp >= r.Since && (r.Before == 0 || cursor) {
+				items = append(items, p)
+			}
+		}
+		historyOrder(items)
+		more := len(items) > r.Limit
+		if more {
+			items = items[len(items)-r.Limit:]
+		}
+		return &chatsdk.HistoryResponse{Status: chatsdk.StatusOK, Publications: items, More: more}
+	})
+
+	const lost = 250
+	publishLost(t, s, sender, lost)
+
+	waitFor(t, "recuperação", func() bool {
+		return recoveredBy(t, received, start) >= lost
+	})
+	if requests.Load() < 3 {
+		t.Fatalf("%d pedidos de histórico, esperadas ao menos 3 páginas", requests.Load())
+	}
+	time.Sleep(500 * time.Millisecond)
+	if n := recoveredBy(t, received, start); n != lost {
+		t.Fatalf("%d publicações recuperadas, esperadas %d", n, lost)
+	}
+	deliveredOnce(t, received, 1, lost)
+}
+
+// TestBackfillReportsTruncation perde mais publicações que WithBackfillLimit
+// numa queda: as mais recentes são recuperadas e o evento traz
+// ErrBackfillTruncated.
+func TestBackfillReportsTruncation(t *testing.T) {
+	s := newServer(t)
+	s.AddChannel("geral")
+	client := newClient(t, s,
+		chatsdk.WithIdleTimeout(200*time.Millisecond),
+		chatsdk.WithBackfillLimit(150))
+	received := &inbox{}
+	client.Subscribe("geral")
+	client.Listen(received.handler())
+	subscribed(t, s, received, "geral")
+	s.Heartbeat()
+	start := len(received.states(0))
+
+	sender := newClient(t, s)
+	_, err := sender.Login(context.Background(), "alice")
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	s.Mute(true)
+	waitFor(t, "desconexão", func() bool {
+		_, ok := received.findState(start, chatsdk.StateDisconnected)
+		return ok
+	})
+	publishLost(t, s, sender, 250)
+
+	waitFor(t, "conexão", func() bool {
+		s.Heartbeat()
+		_, ok := received.findState(start, chatsdk.StateConnected)
+		return ok
+	})
+	event, _ := received.findState(start, chatsdk.StateConnected)
+	if !errors.Is(event.Err, chatsdk.ErrBackfillTruncated) {
+		t.Fatalf("erro da recuperação = %v, esperado ErrBackfillTruncated", event.Err)
+	}
+	if event.Recovered != 150 {
+		t.Fatalf("%d publicações recuperadas, esperadas 150", event.Recovered)
+	}
+
+	// As recuperadas são as 150 mais recentes na ordem do histórico
+	history := s.Publications()
+	historyOrder(history)
+	want := history[len(history)-150].Meta.ID
+	received.mu.Lock()
+	defer received.mu.Unlock()
+	if first := received.publications[0].Meta.ID; first != want {
+		t.Fatalf("primeira recuperada = %q, esperada %q", first, want)
+	}
+}
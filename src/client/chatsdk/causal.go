@@ -0,0 +1,209 @@
+package chatsdk
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// VectorClock conta, por autor, as publicações de um canal que o remetente
+// já tinha visto (ou feito) ao publicar.
+type VectorClock map[string]int64
+
+// causalOrder implementa a entrega causal das publicações (WithCausalOrder):
+// cada publicação leva em meta.vc o relógio vetorial do canal e só é entregue
+// depois das publicações de que depende. As que esperam além de maxWait são
+// entregues assim mesmo, para que uma publicação perdida não trave o canal.
+// O relógio é lido por quem publica e atualizado pela goroutine de Listen.
+type causalOrder struct {
+	maxWait time.Duration
+
+	mu       sync.Mutex
+	channels map[string]*causalChannel
+}
+
+type causalChannel struct {
+	// Publicações entregues (ou feitas por este cliente e aceitas pelo
+	// servidor), por autor
+	delivered VectorClock
+	// Último número dado a uma publicação deste cliente, por usuário,
+	// inclusive as que aguardam resposta
+	reserved map[string]int64
+	// A primeira publicação recebida define o ponto de partida: o que foi
+	// publicado antes da assinatura não é esperado
+	started bool
+	// Publicações aguardando dependências, em ordem de chegada
+	held []heldPublication
+}
+
+type heldPublication struct {
+	publication Publication
+	arrived     time.Time
+}
+
+func newCausalOrder(maxWait time.Duration) *causalOrder {
+	return &causalOrder{
+		maxWait:  maxWait,
+		channels: make(map[string]*causalChannel),
+	}
+}
+
+func (o *causalOrder) channel(name string) *causalChannel {
+	ch := o.channels[name]
+	if ch == nil {
+		ch = &causalChannel{delivered: make(VectorClock), reserved: make(map[string]int64)}
+		o.channels[name] = ch
+	}
+	return ch
+}
+
+// stamp reserva o número da próxima publicação de user no canal e retorna o
+// relógio vetorial dela. A publicação só passa a contar como feita em
+// settle, quando o servidor a aceita.
+func (o *causalOrder) stamp(channel, user string) VectorClock {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	ch := o.channel(channel)
+	own := max(ch.delivered[user], ch.reserved[user]) + 1
+	ch.reserved[user] = own
+	vc := make(VectorClock, len(ch.delivered)+1)
+	for author, n := range ch.delivered {
+		vc[author] = n
+	}
+	vc[user] = own
+	return vc
+}
+
+// settle conclui a publicação own de user com o resultado do envio. Aceita,
+// ela passa a contar como feita; recusada pelo servidor, o número volta a
+// ficar livre se ainda é o último reservado. Sem resposta não se sabe se ela
+// foi publicada, e o número continua reservado.
+func (o *causalOrder) settle(channel, user string, own int64, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	ch := o.channel(channel)
+	var serverErr *ServerError
+	switch {
+	case err == nil:
+		ch.delivered[user] = max(ch.delivered[user], own)
+	case errors.As(err, &serverErr) && ch.reserved[user] == own:
+		ch.reserved[user]--
+	}
+}
+
+// receive recebe uma publicação e retorna as que podem ser entregues agora,
+// em ordem causal. Publicações sem meta.vc são entregues na hora.
+func (o *causalOrder) receive(p Publication, now time.Time) []Publication {
+	if p.Meta == nil || p.Meta.VC == nil {
+		return []Publication{p}
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	ch := o.channel(p.Channel)
+	if ch.started && !ch.ready(p) {
+		ch.held = append(ch.held, heldPublication{publication: p, arrived: now})
+		return nil
+	}
+	ch.started = true
+	ch.deliver(p)
+	return append([]Publication{p}, ch.flush()...)
+}
+
+// expire entrega as publicações que esperam há mais de maxWait, junto com as
+// que dependiam delas.
+func (o *causalOrder) expire(now time.Time) []Publication {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var released []Publication
+	for _, ch := range o.channels {
+		for len(ch.held) > 0 && now.Sub(ch.held[0].arrived) >= o.maxWait {
+			p := ch.held[0].publication
+			ch.held = ch.held[1:]
+			ch.deliver(p)
+			released = append(released, p)
+			released = append(released, ch.flush()...)
+		}
+	}
+	return released
+}
+
+// ready indica se todas as dependências de p já foram entregues: a
+// publicação anterior do mesmo autor e tudo o que ele tinha visto. Um autor
+// desconhecido não tem publicação anterior a esperar.
+func (ch *causalChannel) ready(p Publication) bool {
+	for author, n := range p.Meta.VC {
+		delivered, known := ch.delivered[author]
+		if author == p.User {
+			if known && n > delivered+1 {
+				return false
+			}
+			continue
+		}
+		if n > delivered {
+			return false
+		}
+	}
+	return true
+}
+
+// deliver incorpora o relógio de p. Quando p é entregue sem as dependências
+// (ponto de partida ou espera esgotada), elas deixam de ser esperadas.
+func (ch *causalChannel) deliver(p Publication) {
+	for author, n := range p.Meta.VC {
+		ch.delivered[author] = max(ch.delivered[author], n)
+	}
+}
+
+// flush retira da espera, em ordem causal, as publicações que ficaram prontas.
+func (ch *causalChannel) flush() []Publication {
+	var released []Publication
+	for progress := true; progress; {
+		progress = false
+		for i, held := range ch.held {
+			if !ch.ready(held.publication) {
+				continue
+			}
+			ch.held = append(ch.held[:i], ch.held[i+1:]...)
+			ch.deliver(held.publication)
+			released = append(released, held.publication)
+			progress = true
+			break
+		}
+	}
+	return released
+}
+
+// deliverPublication repassa a publicação ao handler, respeitando a ordem
+// causal quando ativa. Só é chamada pela goroutine de Listen.
+func (c *Client) deliverPublication(h Handler, p Publication) {
+	if c.causal == nil {
+		c.handlePublications(h, []Publication{p})
+		return
+	}
+	c.handlePublications(h, c.causal.receive(p, time.Now()))
+}
+
+// releaseExpired entrega as publicações cujas dependências não chegaram a
+// tempo.
+func (c *Client) releaseExpired(h Handler) {
+	if c.causal != nil {
+		c.handlePublications(h, c.causal.expire(time.Now()))
+	}
+}
+
+func (c *Client) handlePublications(h Handler, publications []Publication) {
+	if h.Publication == nil {
+		return
+	}
+	for _, p := range publications {
+		// A assinatura pode ter sido cancelada durante a espera
+		if c.isSubscribed(p.Channel) {
+			h.Publication(p)
+		}
+	}
+}
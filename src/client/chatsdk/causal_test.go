@@ -0,0 +1,76 @@
+package chatsdk_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"chat-client/chatsdk"
+)
+
+// ownCounts retorna, para cada requisição publish recebida, o contador do
+// próprio autor no relógio vetorial.
+func ownCounts(requests []chatsdk.Payload) []int64 {
+	var counts []int64
+	for _, request := range requests {
+		if publish, ok := request.(*chatsdk.PublishRequest); ok && publish.Meta != nil {
+			counts = append(counts, publish.Meta.VC[publish.User])
+		}
+	}
+	return counts
+}
+
+// TestCausalStampAfterAccept garante que uma publicação recusada pelo
+// servidor não conta no relógio vetorial, e que uma sem resposta continua
+// contando, já que pode ter sido publicada.
+func TestCausalStampAfterAccept(t *testing.T) {
+	s := newServer(t)
+	s.AddChannel("geral")
+	client := newClient(t, s, chatsdk.WithCausalOrder(time.Second))
+	ctx := context.Background()
+	_, err := client.Login(ctx, "alice")
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+
+	s.Handle(chatsdk.ServicePublish, func(chatsdk.Payload) chatsdk.Payload {
+		return &chatsdk.PublishResponse{Status: chatsdk.StatusError, Message: "recusada"}
+	})
+	_, err = client.PublishMessage(ctx, "geral", "recusada")
+	var serverErr *chatsdk.ServerError
+	if !errors.As(err, &serverErr) {
+		t.Fatalf("erro = %v, esperado ServerError", err)
+	}
+	s.Handle(chatsdk.ServicePublish, nil)
+
+	_, err = client.PublishMessage(ctx, "geral", "aceita")
+	if err != nil {
+		t.Fatalf("publicar: %v", err)
+	}
+
+	// Todas as tentativas sem resposta: o servidor publicou, mas o cliente
+	// não sabe
+	s.DropNext(3)
+	_, err = client.PublishMessage(ctx, "geral", "sem resposta")
+	if !errors.Is(err, chatsdk.ErrTimeout) {
+		t.Fatalf("erro = %v, esperado ErrTimeout", err)
+	}
+
+	_, err = client.PublishMessage(ctx, "geral", "seguinte")
+	if err != nil {
+		t.Fatalf("publicar: %v", err)
+	}
+
+	// A recusada e a aceita levam 1; as três tentativas sem resposta, 2
+	want := []int64{1, 1, 2, 2, 2, 3}
+	got := ownCounts(s.Requests())
+	if len(got) != len(want) {
+		t.Fatalf("contadores = %v, esperados %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("contadores = %v, esperados %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,558 @@
+// Package chattest fornece um servidor de chat em memória que fala o mesmo
+// protocolo de src/server/main.js, para exercitar o SDK, o cliente e o bot
+// sem o docker-compose (broker, proxy e servidores Node).
+package chattest
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"chat-client/chatsdk"
+
+	"github.com/pebbe/zmq4"
+)
+
+// Intervalo de cada Poll do loop do servidor
+const pollSlice = 100 * time.Millisecond
+
+// HandlerFunc responde a uma requisição. Retornar nil descarta a resposta,
+// simulando um servidor que recebeu a requisição mas não respondeu.
+type HandlerFunc func(request chatsdk.Payload) chatsdk.Payload
+
+// Server faz o papel do broker (ROUTER) e do proxy (PUB) ao mesmo tempo.
+// Por padrão os serviços se comportam como os de main.js, com estado em
+// memória; Handle substitui um serviço por uma resposta roteirizada.
+type Server struct {
+	// BrokerEndpoint e ProxyEndpoint são os endereços efetivos dos sockets,
+	// para usar com chatsdk.WithBroker e chatsdk.WithProxy
+	BrokerEndpoint string
+	ProxyEndpoint  string
+
+	zmqContext *zmq4.Context
+	router     *zmq4.Socket
+	clock      chatsdk.LamportClock
+
+	// pubMu serializa os envios no socket PUB, usado pelo loop e por Publish
+	pubMu sync.Mutex
+	pub   *zmq4.Socket
+	muted bool
+
+	// mu protege o estado abaixo, lido pelos testes enquanto o loop roda
+	mu           sync.Mutex
+	users        []string
+	channels     []string
+	publications []chatsdk.Publication
+	messages     []chatsdk.PrivateMessage
+	requests     []chatsdk.Payload
+	replies      map[string]chatsdk.Payload
+	handlers     map[string]HandlerFunc
+	drop         int
+
+	closeOnce sync.Once
+	done      chan struct{}
+	stopped   chan struct{}
+}
+
+// NewServer cria o servidor em portas livres de 127.0.0.1 e começa a atender.
+func NewServer() (*Server, error) {
+	zmqContext, err := zmq4.NewContext()
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar contexto ZMQ: %v", err)
+	}
+
+	s := &Server{
+		zmqContext: zmqContext,
+		replies:    make(map[string]chatsdk.Payload),
+		handlers:   make(map[string]HandlerFunc),
+		done:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+
+	s.router, s.BrokerEndpoint, err = bind(zmqContext, zmq4.ROUTER)
+	if err != nil {
+		zmqContext.Term()
+		return nil, err
+	}
+	s.pub, s.ProxyEndpoint, err = bind(zmqContext, zmq4.PUB)
+	if err != nil {
+		s.router.Close()
+		zmqContext.Term()
+		return nil, err
+	}
+
+	go s.serve()
+	return s, nil
+}
+
+func bind(zmqContext *zmq4.Context, socketType zmq4.Type) (*zmq4.Socket, string, error) {
+	socket, err := zmqContext.NewSocket(socketType)
+	if err != nil {
+		return nil, "", fmt.Errorf("erro ao criar socket %v: %v", socketType, err)
+	}
+
+	err = socket.Bind("tcp://127.0.0.1:*")
+	if err != nil {
+		socket.Close()
+		return nil, "", fmt.Errorf("erro ao fazer bind do socket %v: %v", socketType, err)
+	}
+
+	endpoint, err := socket.GetLastEndpoint()
+	if err != nil {
+		socket.Close()
+		return nil, "", fmt.Errorf("erro ao obter endereço do socket %v: %v", socketType, err)
+	}
+	return socket, endpoint, nil
+}
+
+// Options retorna as opções que apontam um chatsdk.Client para este servidor.
+func (s *Server) Options() []chatsdk.Option {
+	return []chatsdk.Option{
+		chatsdk.WithBroker(s.BrokerEndpoint),
+		chatsdk.WithProxy(s.ProxyEndpoint),
+	}
+}
+
+// Close para o loop e fecha os sockets.
+func (s *Server) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.done)
+		<-s.stopped
+
+		s.router.SetLinger(0)
+		s.router.Close()
+		s.pubMu.Lock()
+		s.pub.SetLinger(0)
+		s.pub.Close()
+		s.pubMu.Unlock()
+		err = s.zmqContext.Term()
+	})
+	return err
+}
+
+// Handle substitui a implementação padrão de um serviço; fn nil a restaura.
+// Timestamp e clock zerados na resposta são preenchidos pelo servidor.
+func (s *Server) Handle(service string, fn HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if fn == nil {
+		delete(s.handlers, service)
+		return
+	}
+	s.handlers[service] = fn
+}
+
+// DropNext descarta as respostas das próximas n requisições, que ainda são
+// processadas e registradas. Serve para exercitar as retentativas do cliente.
+func (s *Server) DropNext(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.drop += n
+}
+
+// Publish envia uma mensagem no tópico, como o servidor faz no proxy.
+func (s *Server) Publish(topic string, p chatsdk.Payload) error {
+	s.stamp(p)
+	encoded, err := chatsdk.Encode(p)
+	if err != nil {
+		return err
+	}
+
+	s.pubMu.Lock()
+	defer s.pubMu.Unlock()
+	if s.muted {
+		return nil
+	}
+	_, err = s.pub.SendMessage(topic, encoded)
+	if err != nil {
+		return fmt.Errorf("erro ao publicar em '%s': %v", topic, err)
+	}
+	return nil
+}
+
+// Mute interrompe (ou retoma) os envios no socket PUB, inclusive heartbeats,
+// simulando uma queda do proxy. As requisições continuam sendo atendidas e
+// as publicações ficam no histórico.
+func (s *Server) Mute(muted bool) {
+	s.pubMu.Lock()
+	defer s.pubMu.Unlock()
+	s.muted = muted
+}
+
+// Heartbeat publica um heartbeat, como os servidores fazem periodicamente.
+// Depois do primeiro, o cliente trata silêncio como queda da assinatura.
+func (s *Server) Heartbeat() error {
+	return s.Publish(chatsdk.HeartbeatTopic, &chatsdk.Heartbeat{Server: "chattest"})
+}
+
+// AddUser registra um usuário como se ele tivesse feito login.
+func (s *Server) AddUser(user string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users = appendUnique(s.users, user)
+}
+
+// AddChannel cria um canal sem passar pelo serviço channel.
+func (s *Server) AddChannel(channel string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.channels = appendUnique(s.channels, channel)
+}
+
+// Requests retorna as requisições recebidas, na ordem de chegada.
+func (s *Server) Requests() []chatsdk.Payload {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]chatsdk.Payload(nil), s.requests...)
+}
+
+// Users retorna os usuários registrados, na ordem do primeiro login.
+func (s *Server) Users() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.users...)
+}
+
+// Channels retorna os canais criados, na ordem de criação.
+func (s *Server) Channels() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.channels...)
+}
+
+// Publications retorna as publicações aceitas pelo serviço publish.
+func (s *Server) Publications() []chatsdk.Publication {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]chatsdk.Publication(nil), s.publications...)
+}
+
+// Messages retorna as mensagens privadas aceitas pelo serviço message.
+func (s *Server) Messages() []chatsdk.PrivateMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]chatsdk.PrivateMessage(nil), s.messages...)
+}
+
+// serve atende o socket ROUTER até Close. Cada requisição de um REQ chega
+// como [identidade, "", corpo] e a resposta volta no mesmo formato.
+func (s *Server) serve() {
+	defer close(s.stopped)
+
+	poller := zmq4.NewPoller()
+	poller.Add(s.router, zmq4.POLLIN)
+
+	for {
+		select {
+		case <-s.done:
+			return
+		default:
+		}
+
+		polled, err := poller.Poll(pollSlice)
+		if err != nil || len(polled) == 0 {
+			continue
+		}
+
+		frames, err := s.router.RecvMessageBytes(0)
+		if err != nil || len(frames) != 3 {
+			continue
+		}
+
+		reply := s.handle(frames[2])
+		if reply == nil {
+			continue
+		}
+		s.router.SendMessage(frames[0], "", reply)
+	}
+}
+
+// handle decodifica a requisição e retorna a resposta serializada, ou nil
+// quando ela deve ser descartada.
+func (s *Server) handle(body []byte) []byte {
+	request, err := chatsdk.DecodeRequest(body)
+	if err != nil {
+		// main.js responde assim a qualquer exceção no tratamento
+		return s.encode(&chatsdk.ErrorResponse{
+			Status:      chatsdk.StatusError,
+			Description: "Erro interno do servidor",
+		})
+	}
+	s.clock.Merge(s.requestClock(request))
+
+	s.mu.Lock()
+	s.requests = append(s.requests, request)
+	handler, scripted := s.handlers[request.Service()]
+	drop := s.drop > 0
+	if drop {
+		s.drop--
+	}
+	s.mu.Unlock()
+
+	var response chatsdk.Payload
+	if scripted {
+		response = handler(request)
+	} else {
+		response = s.respondOnce(request)
+	}
+	if response == nil || drop {
+		return nil
+	}
+	if header := headerOf(response); header != nil && header.RequestID == "" {
+		header.RequestID = headerOf(request).RequestID
+	}
+	return s.encode(response)
+}
+
+func (s *Server) encode(p chatsdk.Payload) []byte {
+	s.stamp(p)
+	encoded, err := chatsdk.Encode(p)
+	if err != nil {
+		return nil
+	}
+	return encoded
+}
+
+// respond implementa os serviços como em main.js, inclusive os textos de erro.
+func (s *Server) respond(request chatsdk.Payload) chatsdk.Payload {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r := request.(type) {
+	case *chatsdk.LoginRequest:
+		if strings.TrimSpace(r.User) == "" {
+			return &chatsdk.LoginResponse{Status: chatsdk.StatusError, Description: "Nome de usuário inválido"}
+		}
+		s.users = appendUnique(s.users, r.User)
+		return &chatsdk.LoginResponse{Status: chatsdk.StatusSuccess}
+
+	case *chatsdk.UsersRequest:
+		return &chatsdk.UsersResponse{Users: append([]string{}, s.users...)}
+
+	case *chatsdk.ChannelRequest:
+		if strings.TrimSpace(r.Channel) == "" {
+			return &chatsdk.ChannelResponse{Status: chatsdk.StatusError, Description: "Nome do canal inválido"}
+		}
+		if contains(s.channels, r.Channel) {
+			return &chatsdk.ChannelResponse{Status: chatsdk.StatusError, Description: "Canal já existe"}
+		}
+		s.channels = append(s.channels, r.Channel)
+		return &chatsdk.ChannelResponse{Status: chatsdk.StatusSuccess}
+
+	case *chatsdk.ChannelsRequest:
+		return &chatsdk.ChannelsResponse{Channels: append([]string{}, s.channels...)}
+
+	case *chatsdk.PublishRequest:
+		if !contains(s.channels, r.Channel) {
+			return &chatsdk.PublishResponse{Status: chatsdk.StatusError, Message: "Canal não existe"}
+		}
+		publication := chatsdk.Publication{
+			User:    r.User,
+			Channel: r.Channel,
+			Message: r.Message,
+			Meta:    r.Meta,
+			Header:  chatsdk.Header{Timestamp: r.Timestamp},
+		}
+		// Publicar antes de guardar, para que o histórico tenha o clock; só
+		// o histórico leva o request_id, como em main.js
+		s.Publish(r.Channel, &publication)
+		publication.RequestID = r.RequestID
+		s.publications = append(s.publications, publication)
+		return &chatsdk.PublishResponse{Status: chatsdk.StatusOK}
+
+	case *chatsdk.MessageRequest:
+		if !contains(s.users, r.Dst) {
+			return &chatsdk.MessageResponse{Status: chatsdk.StatusError, Message: "Usuário de destino não existe"}
+		}
+		message := chatsdk.PrivateMessage{
+			Src:     r.Src,
+			Dst:     r.Dst,
+			Message: r.Message,
+			Meta:    r.Meta,
+			Header:  chatsdk.Header{Timestamp: r.Timestamp},
+		}
+		s.Publish(r.Dst, &message)
+		message.RequestID = r.RequestID
+		s.messages = append(s.messages, message)
+		return &chatsdk.MessageResponse{Status: chatsdk.StatusOK}
+
+	case *chatsdk.HistoryRequest:
+		if !contains(s.channels, r.Channel) {
+			return &chatsdk.HistoryResponse{Status: chatsdk.StatusError, Description: "Canal não existe"}
+		}
+		var publications []chatsdk.Publication
+		for _, p := range s.publications {
+			if p.Channel == r.Channel {
+				publications = append(publications, p)
+			}
+		}
+		cursor := historyCursor{r.Since, r.Before, r.BeforeID, r.Limit}
+		page, more := historyPage(publications, cursor, func(p chatsdk.Publication) chatsdk.Header { return p.Header })
+		return &chatsdk.HistoryResponse{Status: chatsdk.StatusOK, Publications: page, More: more}
+
+	case *chatsdk.DMHistoryRequest:
+		if !contains(s.users, r.User) || !contains(s.users, r.Peer) {
+			return &chatsdk.DMHistoryResponse{Status: chatsdk.StatusError, Description: "Usuário não existe"}
+		}
+		var messages []chatsdk.PrivateMessage
+		for _, m := range s.messages {
+			if (m.Src == r.User && m.Dst == r.Peer) || (m.Src == r.Peer && m.Dst == r.User) {
+				messages = append(messages, m)
+			}
+		}
+		cursor := historyCursor{r.Since, r.Before, r.BeforeID, r.Limit}
+		page, more := historyPage(messages, cursor, func(m chatsdk.PrivateMessage) chatsdk.Header { return m.Header })
+		return &chatsdk.DMHistoryResponse{Status: chatsdk.StatusOK, Messages: page, More: more}
+	}
+
+	return &chatsdk.ErrorResponse{Status: chatsdk.StatusError, Description: "Serviço não encontrado"}
+}
+
+// Serviços cujas respostas são guardadas por request_id, como em main.js
+var mutating = map[string]bool{
+	chatsdk.ServiceLogin:   true,
+	chatsdk.ServiceChannel: true,
+	chatsdk.ServicePublish: true,
+	chatsdk.ServiceMessage: true,
+}
+
+// respondOnce responde a um reenvio (mesmo request_id) de um serviço que
+// altera o estado repetindo a resposta original, marcada como duplicata, sem
+// repetir o efeito.
+func (s *Server) respondOnce(request chatsdk.Payload) chatsdk.Payload {
+	id := headerOf(request).RequestID
+	if id == "" || !mutating[request.Service()] {
+		return s.respond(request)
+	}
+
+	s.mu.Lock()
+	cached, ok := s.replies[id]
+	s.mu.Unlock()
+	if ok {
+		return replay(cached)
+	}
+
+	response := s.respond(request)
+	s.mu.Lock()
+	s.replies[id] = replay(response)
+	s.mu.Unlock()
+	return response
+}
+
+// replay copia a resposta marcando-a como duplicata, com timestamp e clock
+// zerados para serem preenchidos de novo.
+func replay(p chatsdk.Payload) chatsdk.Payload {
+	v := reflect.New(reflect.TypeOf(p).Elem())
+	v.Elem().Set(reflect.ValueOf(p).Elem())
+	copied := v.Interface().(chatsdk.Payload)
+	if header := headerOf(copied); header != nil {
+		header.Timestamp = 0
+		header.Clock = 0
+		header.Duplicate = true
+	}
+	return copied
+}
+
+// historyCursor reúne os campos de paginação de history e dm_history.
+type historyCursor struct {
+	since, before int64
+	beforeID      string
+	limit         int
+}
+
+// historyBefore indica se a vem antes de b na ordem do histórico: por
+// timestamp e, no mesmo milissegundo, por request_id.
+func historyBefore(a, b chatsdk.Header) bool {
+	if a.Timestamp != b.Timestamp {
+		return a.Timestamp < b.Timestamp
+	}
+	return a.RequestID < b.RequestID
+}
+
+// historyPage seleciona a página como historyPage de main.js: filtra por
+// since e pelo cursor, ordena por timestamp e request_id e fica com os mais
+// recentes. Retorna também se sobraram itens mais antigos.
+func historyPage[T any](items []T, c historyCursor, header func(T) chatsdk.Header) ([]T, bool) {
+	limit := c.limit
+	if limit <= 0 {
+		limit = 100
+	}
+	limit = min(limit, 500)
+
+	cursor := chatsdk.Header{Timestamp: c.before, RequestID: c.beforeID}
+	var selected []T
+	for _, item := range items {
+		h := header(item)
+		if h.Timestamp >= c.since && (c.before == 0 || historyBefore(h, cursor)) {
+			selected = append(selected, item)
+		}
+	}
+	sort.SliceStable(selected, func(i, j int) bool {
+		return historyBefore(header(selected[i]), header(selected[j]))
+	})
+
+	if len(selected) > limit {
+		return selected[len(selected)-limit:], true
+	}
+	return selected, false
+}
+
+// stamp preenche timestamp e clock quando não foram definidos, como o
+// servidor faz em todas as mensagens que envia.
+func (s *Server) stamp(p chatsdk.Payload) {
+	header := headerOf(p)
+	if header == nil {
+		return
+	}
+	if header.Timestamp == 0 {
+		header.Timestamp = time.Now().UnixMilli()
+	}
+	if header.Clock == 0 {
+		header.Clock = s.clock.Tick()
+	}
+}
+
+func (s *Server) requestClock(p chatsdk.Payload) int64 {
+	if header := headerOf(p); header != nil {
+		return header.Clock
+	}
+	return 0
+}
+
+// headerOf acessa o Header embutido nos tipos do protocolo, cujo método de
+// acesso não é exportado por chatsdk.
+func headerOf(p chatsdk.Payload) *chatsdk.Header {
+	v := reflect.ValueOf(p)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	field := v.Elem().FieldByName("Header")
+	if !field.IsValid() {
+		return nil
+	}
+	header, ok := field.Addr().Interface().(*chatsdk.Header)
+	if !ok {
+		return nil
+	}
+	return header
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func appendUnique(list []string, value string) []string {
+	if contains(list, value) {
+		return list
+	}
+	return append(list, value)
+}
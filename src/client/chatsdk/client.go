@@ -0,0 +1,295 @@
+// Package chatsdk implementa o protocolo do sistema de mensagens distribuído
+// (requisições via broker e publicações via proxy) para clientes em Go.
+package chatsdk
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/pebbe/zmq4"
+)
+
+// Client mantém os sockets REQ (broker) e SUB (proxy) de um usuário.
+type Client struct {
+	zmqContext *zmq4.Context
+	subSocket  *zmq4.Socket
+	opts       options
+	clock      LamportClock
+	hlc        HybridClock
+	timeSync   timeSync
+	// Relógios vetoriais dos canais (nil sem WithCausalOrder)
+	causal *causalOrder
+
+	// reqMu serializa as requisições: o socket REQ só aceita um envio por
+	// vez e é recriado pelo Lazy Pirate quando uma resposta não chega
+	reqMu     sync.Mutex
+	reqSocket *zmq4.Socket
+	closed    bool
+
+	// mu protege username, lido também pela goroutine de Listen
+	mu       sync.RWMutex
+	username string
+
+	// subMu protege o conjunto de tópicos; o socket SUB em si só é usado
+	// pela goroutine de Listen, que aplica as mudanças pendentes
+	subMu         sync.Mutex
+	subscriptions map[string]bool
+	pendingSubs   []subOp
+
+	listenOnce sync.Once
+	closeOnce  sync.Once
+	done       chan struct{}
+	listenDone chan struct{}
+}
+
+// New cria o contexto e os sockets ZMQ. A conexão é feita por Connect.
+func New(opts ...Option) (*Client, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	zmqContext, err := zmq4.NewContext()
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar contexto ZMQ: %v", err)
+	}
+
+	subSocket, err := zmqContext.NewSocket(zmq4.SUB)
+	if err != nil {
+		zmqContext.Term()
+		return nil, fmt.Errorf("erro ao criar socket SUB: %v", err)
+	}
+
+	var causal *causalOrder
+	if o.causalWait > 0 {
+		causal = newCausalOrder(o.causalWait)
+	}
+
+	return &Client{
+		zmqContext:    zmqContext,
+		subSocket:     subSocket,
+		opts:          o,
+		causal:        causal,
+		username:      o.username,
+		subscriptions: make(map[string]bool),
+		done:          make(chan struct{}),
+		listenDone:    make(chan struct{}),
+	}, nil
+}
+
+// Connect conecta o socket REQ ao broker e o socket SUB ao proxy.
+func (c *Client) Connect() error {
+	// Conectar ao broker
+	c.reqMu.Lock()
+	err := c.openReqSocket()
+	c.reqMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// Conectar ao proxy. As subscriptions são aplicadas por Listen.
+	err = c.subSocket.Connect(c.opts.proxyEndpoint)
+	if err != nil {
+		return fmt.Errorf("erro ao conectar ao proxy: %v", err)
+	}
+
+	return nil
+}
+
+// Close encerra a goroutine de Listen, fecha os sockets e o contexto ZMQ.
+func (c *Client) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		// Parar a goroutine de Listen antes de fechar o socket SUB. Se
+		// Listen nunca foi chamado, o Once impede que seja iniciado depois.
+		close(c.done)
+		c.listenOnce.Do(func() { close(c.listenDone) })
+		<-c.listenDone
+
+		c.reqMu.Lock()
+		c.closed = true
+		c.closeReqSocket()
+		c.reqMu.Unlock()
+
+		// O socket SUB pode ter ficado nulo se a última reconexão falhou
+		if c.subSocket != nil {
+			c.subSocket.SetLinger(0)
+			c.subSocket.Close()
+		}
+		err = c.zmqContext.Term()
+	})
+	return err
+}
+
+// Username retorna o usuário do último Login (ou o definido por WithUsername).
+func (c *Client) Username() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.username
+}
+
+// Clock retorna o valor atual do relógio lógico.
+func (c *Client) Clock() int64 {
+	return c.clock.Value()
+}
+
+// HLC retorna o valor atual do relógio lógico híbrido.
+func (c *Client) HLC() HLC {
+	return c.hlc.Value()
+}
+
+// Login registra o usuário no servidor. Com username vazio usa o nome de
+// WithUsername.
+func (c *Client) Login(ctx context.Context, username string) (*LoginResponse, error) {
+	if username == "" {
+		username = c.opts.username
+	}
+
+	response, err := call[*LoginResponse](ctx, c, &LoginRequest{User: username})
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	previous := c.username
+	c.username = username
+	c.mu.Unlock()
+
+	// Mensagens privadas são publicadas com o destinatário como tópico
+	if previous != "" && previous != username {
+		c.Unsubscribe(previous)
+	}
+	c.Subscribe(username)
+
+	return response, nil
+}
+
+// ListUsers retorna os usuários cadastrados no servidor.
+func (c *Client) ListUsers(ctx context.Context) (*UsersResponse, error) {
+	return call[*UsersResponse](ctx, c, &UsersRequest{})
+}
+
+// CreateChannel cria um novo canal.
+func (c *Client) CreateChannel(ctx context.Context, channelName string) (*ChannelResponse, error) {
+	return call[*ChannelResponse](ctx, c, &ChannelRequest{Channel: channelName})
+}
+
+// ListChannels retorna os canais existentes.
+func (c *Client) ListChannels(ctx context.Context) (*ChannelsResponse, error) {
+	return call[*ChannelsResponse](ctx, c, &ChannelsRequest{})
+}
+
+// PublishMessage publica uma mensagem em um canal como o usuário logado.
+func (c *Client) PublishMessage(ctx context.Context, channel, message string) (*PublishResponse, error) {
+	return c.PublishMessageWithMeta(ctx, channel, message, nil)
+}
+
+// PublishMessageWithMeta publica uma mensagem levando meta, que o servidor
+// repassa sem alterar na Publication entregue aos assinantes. meta.hlc (e,
+// com WithCausalOrder, meta.vc) é preenchido pelo Client.
+func (c *Client) PublishMessageWithMeta(ctx context.Context, channel, message string, meta *Meta) (*PublishResponse, error) {
+	user := c.Username()
+	stamped := c.stampPublication(channel, user, meta)
+	response, err := call[*PublishResponse](ctx, c, &PublishRequest{
+		User:    user,
+		Channel: channel,
+		Message: message,
+		Meta:    stamped,
+	})
+	c.settlePublication(channel, user, stamped, err)
+	return response, err
+}
+
+// SendPrivateMessage envia uma mensagem privada para destUser.
+func (c *Client) SendPrivateMessage(ctx context.Context, destUser, message string) (*MessageResponse, error) {
+	return c.SendPrivateMessageWithMeta(ctx, destUser, message, nil)
+}
+
+// SendPrivateMessageWithMeta envia uma mensagem privada levando meta, que o
+// servidor repassa sem alterar na PrivateMessage entregue ao destinatário.
+// meta.hlc é preenchido pelo Client.
+func (c *Client) SendPrivateMessageWithMeta(ctx context.Context, destUser, message string, meta *Meta) (*MessageResponse, error) {
+	return call[*MessageResponse](ctx, c, &MessageRequest{
+		Src:     c.Username(),
+		Dst:     destUser,
+		Message: message,
+		Meta:    c.stampMeta(meta),
+	})
+}
+
+// stampMeta retorna uma cópia de meta com o relógio híbrido do envio.
+func (c *Client) stampMeta(meta *Meta) *Meta {
+	stamped := &Meta{}
+	if meta != nil {
+		*stamped = *meta
+	}
+	hlc := c.hlc.Now()
+	stamped.HLC = &hlc
+	return stamped
+}
+
+// stampPublication carimba meta como stampMeta e, com a entrega causal
+// ativa, acrescenta o relógio vetorial da publicação de user no canal.
+func (c *Client) stampPublication(channel, user string, meta *Meta) *Meta {
+	stamped := c.stampMeta(meta)
+	if c.causal != nil {
+		stamped.VC = c.causal.stamp(channel, user)
+	}
+	return stamped
+}
+
+// settlePublication informa à entrega causal o resultado do envio de uma
+// publicação carimbada por stampPublication.
+func (c *Client) settlePublication(channel, user string, meta *Meta, err error) {
+	if c.causal != nil && meta != nil && meta.VC != nil {
+		c.causal.settle(channel, user, meta.VC[user], err)
+	}
+}
+
+// HistoryQuery seleciona uma página do histórico. Timestamps em
+// milissegundos; campos zerados não restringem a busca.
+type HistoryQuery struct {
+	// Só itens com timestamp a partir de Since
+	Since int64
+	// Só itens anteriores ao cursor (Before, BeforeID), na ordem por
+	// timestamp e request_id. BeforeID desempata itens do mesmo
+	// milissegundo; o clock não serve, já que cada réplica atribui o seu
+	Before   int64
+	BeforeID string
+	// Máximo de itens, os mais recentes (zero usa o padrão do servidor)
+	Limit int
+}
+
+// History retorna uma página das publicações do canal, ordenada por
+// timestamp e request_id. Para a página anterior, use NextPage enquanto
+// More for true.
+func (c *Client) History(ctx context.Context, channel string, q HistoryQuery) (*HistoryResponse, error) {
+	return call[*HistoryResponse](ctx, c, &HistoryRequest{
+		Channel:  channel,
+		Since:    q.Since,
+		Before:   q.Before,
+		BeforeID: q.BeforeID,
+		Limit:    q.Limit,
+	})
+}
+
+// DMHistory retorna uma página das mensagens privadas trocadas entre o
+// usuário logado e peer, com a mesma paginação de History.
+func (c *Client) DMHistory(ctx context.Context, peer string, q HistoryQuery) (*DMHistoryResponse, error) {
+	return call[*DMHistoryResponse](ctx, c, &DMHistoryRequest{
+		User:     c.Username(),
+		Peer:     peer,
+		Since:    q.Since,
+		Before:   q.Before,
+		BeforeID: q.BeforeID,
+		Limit:    q.Limit,
+	})
+}
+
+// NextPage retorna a consulta da página anterior à que tem oldest como item
+// mais antigo.
+func (q HistoryQuery) NextPage(oldest Header) HistoryQuery {
+	q.Before, q.BeforeID = oldest.Timestamp, oldest.RequestID
+	return q
+}
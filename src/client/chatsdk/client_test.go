@@ -0,0 +1,223 @@
+package chatsdk_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"chat-client/chatsdk"
+	"chat-client/chatsdk/chattest"
+)
+
+// Prazo dos testes para esperar uma mensagem ou mudança de estado
+const waitTimeout = 5 * time.Second
+
+func newServer(t *testing.T) *chattest.Server {
+	t.Helper()
+	s, err := chattest.NewServer()
+	if err != nil {
+		t.Fatalf("erro ao criar servidor: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// newClient conecta um cliente ao servidor de teste, com retentativas curtas.
+func newClient(t *testing.T, s *chattest.Server, opts ...chatsdk.Option) *chatsdk.Client {
+	t.Helper()
+	options := append(s.Options(), chatsdk.WithRetryPolicy(chatsdk.RetryPolicy{
+		Timeout:  500 * time.Millisecond,
+		Attempts: 3,
+		Backoff:  10 * time.Millisecond,
+	}))
+	client, err := chatsdk.New(append(options, opts...)...)
+	if err != nil {
+		t.Fatalf("erro ao criar cliente: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	err = client.Connect()
+	if err != nil {
+		t.Fatalf("erro ao conectar: %v", err)
+	}
+	return client
+}
+
+// waitFor espera cond ficar verdadeira, falhando depois de waitTimeout.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(waitTimeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("tempo esgotado esperando %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// inbox guarda o que Listen entrega, para os testes consultarem de outra
+// goroutine.
+type inbox struct {
+	mu           sync.Mutex
+	publications []chatsdk.Publication
+	messages     []chatsdk.PrivateMessage
+	events       []chatsdk.ConnectionEvent
+}
+
+func (in *inbox) handler() chatsdk.Handler {
+	return chatsdk.Handler{
+		Publication: func(p chatsdk.Publication) {
+			in.mu.Lock()
+			defer in.mu.Unlock()
+			in.publications = append(in.publications, p)
+		},
+		PrivateMessage: func(m chatsdk.PrivateMessage) {
+			in.mu.Lock()
+			defer in.mu.Unlock()
+			in.messages = append(in.messages, m)
+		},
+		State: func(e chatsdk.ConnectionEvent) {
+			in.mu.Lock()
+			defer in.mu.Unlock()
+			in.events = append(in.events, e)
+		},
+	}
+}
+
+func (in *inbox) counts() (publications, messages int) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return len(in.publications), len(in.messages)
+}
+
+// subscribed espera a assinatura do canal chegar ao socket SUB, publicando
+// até que uma publicação de teste seja entregue.
+func subscribed(t *testing.T, s *chattest.Server, in *inbox, channel string) {
+	t.Helper()
+	waitFor(t, "assinatura de "+channel, func() bool {
+		s.Publish(channel, &chatsdk.Publication{Channel: channel, User: "chattest", Message: "ping"})
+		n, _ := in.counts()
+		return n > 0
+	})
+	// Descarta as publicações de teste que ainda estejam a caminho
+	time.Sleep(50 * time.Millisecond)
+	in.mu.Lock()
+	in.publications = nil
+	in.mu.Unlock()
+}
+
+func TestLamportClockConcurrent(t *testing.T) {
+	var clock chatsdk.LamportClock
+	const goroutines, rounds = 8, 1000
+
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				clock.Tick()
+				clock.Merge(0)
+				clock.Value()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Cada Tick e cada Merge(0) avançam exatamente uma unidade
+	if got, want := clock.Value(), int64(2*goroutines*rounds); got != want {
+		t.Fatalf("relógio = %d, esperado %d", got, want)
+	}
+}
+
+// TestConcurrentSendAndReceive envia publicações e mensagens privadas de
+// várias goroutines enquanto Listen entrega o que chega e outras goroutines
+// leem o relógio, o usuário e mudam as assinaturas. Deve ser executado com
+// go test -race.
+func TestConcurrentSendAndReceive(t *testing.T) {
+	s := newServer(t)
+	s.AddChannel("geral")
+
+	receiver := newClient(t, s)
+	_, err := receiver.Login(context.Background(), "bob")
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	received := &inbox{}
+	receiver.Subscribe("geral")
+	receiver.Listen(received.handler())
+	subscribed(t, s, received, "geral")
+
+	sender := newClient(t, s)
+	_, err = sender.Login(context.Background(), "alice")
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+
+	const goroutines, rounds = 4, 10
+	stop := make(chan struct{})
+	var readers sync.WaitGroup
+	for _, client := range []*chatsdk.Client{sender, receiver} {
+		readers.Add(1)
+		go func(client *chatsdk.Client) {
+			defer readers.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				client.Clock()
+				client.HLC()
+				client.Username()
+				client.Subscriptions()
+				topic := fmt.Sprintf("extra%d", i%3)
+				client.Subscribe(topic)
+				client.Unsubscribe(topic)
+				time.Sleep(time.Millisecond)
+			}
+		}(client)
+	}
+
+	var senders sync.WaitGroup
+	errs := make(chan error, 2*goroutines*rounds)
+	for g := 0; g < goroutines; g++ {
+		senders.Add(1)
+		go func(g int) {
+			defer senders.Done()
+			for i := 0; i < rounds; i++ {
+				_, err := sender.PublishMessage(context.Background(), "geral", fmt.Sprintf("pub %d-%d", g, i))
+				if err != nil {
+					errs <- err
+				}
+				_, err = sender.SendPrivateMessage(context.Background(), "bob", fmt.Sprintf("dm %d-%d", g, i))
+				if err != nil {
+					errs <- err
+				}
+			}
+		}(g)
+	}
+	senders.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("envio: %v", err)
+	}
+
+	total := goroutines * rounds
+	waitFor(t, "entregas", func() bool {
+		publications, messages := received.counts()
+		return publications == total && messages == total
+	})
+	close(stop)
+	readers.Wait()
+
+	// O relógio do receptor passou do clock de todas as mensagens recebidas
+	received.mu.Lock()
+	defer received.mu.Unlock()
+	for _, p := range received.publications {
+		if p.Clock >= receiver.Clock() {
+			t.Errorf("clock da publicação %d não é menor que o do receptor %d", p.Clock, receiver.Clock())
+		}
+	}
+}
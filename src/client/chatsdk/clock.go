@@ -0,0 +1,84 @@
+package chatsdk
+
+import (
+	"math"
+	"sync"
+)
+
+// LamportClock é o relógio lógico do processo (Parte 4): incrementa antes de
+// cada envio e, a cada recebimento, avança para max(local, recebido) + 1.
+// É seguro para uso concorrente (REPL e goroutine do socket SUB).
+type LamportClock struct {
+	mu    sync.Mutex
+	value int64
+}
+
+// Tick incrementa o relógio para um envio e retorna o novo valor.
+func (c *LamportClock) Tick() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.value++
+	return c.value
+}
+
+// Merge incorpora o relógio recebido em uma mensagem e retorna o novo valor.
+func (c *LamportClock) Merge(received int64) int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.value = max(c.value, received) + 1
+	return c.value
+}
+
+// Value retorna o valor atual sem alterá-lo.
+func (c *LamportClock) Value() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.value
+}
+
+// ClockValue converte um número decodificado pelo msgpack para int64. O
+// msgpack escolhe a menor representação (int8, uint16, ...) e o servidor Node
+// pode enviar inteiros grandes como float64, então todas são aceitas.
+func ClockValue(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int8:
+		return int64(n), true
+	case int16:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint:
+		return uintValue(uint64(n))
+	case uint8:
+		return int64(n), true
+	case uint16:
+		return int64(n), true
+	case uint32:
+		return int64(n), true
+	case uint64:
+		return uintValue(n)
+	case float32:
+		return floatValue(float64(n))
+	case float64:
+		return floatValue(n)
+	}
+	return 0, false
+}
+
+func uintValue(n uint64) (int64, bool) {
+	if n > math.MaxInt64 {
+		return 0, false
+	}
+	return int64(n), true
+}
+
+func floatValue(f float64) (int64, bool) {
+	if f != math.Trunc(f) || f > math.MaxInt64 || f < math.MinInt64 {
+		return 0, false
+	}
+	return int64(f), true
+}
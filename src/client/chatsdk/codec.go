@@ -0,0 +1,226 @@
+package chatsdk
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+
+	msgpack "github.com/vmihailenco/msgpack/v5"
+	"github.com/vmihailenco/msgpack/v5/msgpcode"
+)
+
+type envelope struct {
+	Service string      `msgpack:"service"`
+	Data    interface{} `msgpack:"data"`
+}
+
+type rawEnvelope struct {
+	Service string             `msgpack:"service"`
+	Data    msgpack.RawMessage `msgpack:"data"`
+}
+
+// Tipos recebidos pelo cliente (respostas do broker e publicações do proxy)
+var decoders = map[string]func() Payload{
+	ServiceLogin:          func() Payload { return new(LoginResponse) },
+	ServiceUsers:          func() Payload { return new(UsersResponse) },
+	ServiceChannel:        func() Payload { return new(ChannelResponse) },
+	ServiceChannels:       func() Payload { return new(ChannelsResponse) },
+	ServicePublish:        func() Payload { return new(PublishResponse) },
+	ServiceMessage:        func() Payload { return new(MessageResponse) },
+	ServiceHistory:        func() Payload { return new(HistoryResponse) },
+	ServiceDMHistory:      func() Payload { return new(DMHistoryResponse) },
+	ServiceError:          func() Payload { return new(ErrorResponse) },
+	ServicePublication:    func() Payload { return new(Publication) },
+	ServicePrivateMessage: func() Payload { return new(PrivateMessage) },
+	ServiceHeartbeat:      func() Payload { return new(Heartbeat) },
+}
+
+// Tipos recebidos pelo servidor
+var requestDecoders = map[string]func() Payload{
+	ServiceLogin:     func() Payload { return new(LoginRequest) },
+	ServiceUsers:     func() Payload { return new(UsersRequest) },
+	ServiceChannel:   func() Payload { return new(ChannelRequest) },
+	ServiceChannels:  func() Payload { return new(ChannelsRequest) },
+	ServicePublish:   func() Payload { return new(PublishRequest) },
+	ServiceMessage:   func() Payload { return new(MessageRequest) },
+	ServiceHistory:   func() Payload { return new(HistoryRequest) },
+	ServiceDMHistory: func() Payload { return new(DMHistoryRequest) },
+}
+
+// Encode serializa o payload no envelope {service, data}.
+func Encode(p Payload) ([]byte, error) {
+	encoded, err := msgpack.Marshal(envelope{Service: p.Service(), Data: p})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao serializar mensagem: %v", err)
+	}
+	return encoded, nil
+}
+
+// Decode desserializa uma resposta ou publicação escolhendo o tipo pelo
+// campo service. Campos obrigatórios ausentes ou com tipo inválido geram erro.
+func Decode(b []byte) (Payload, error) {
+	return decode(b, decoders)
+}
+
+// DecodeRequest desserializa uma requisição enviada por um cliente.
+func DecodeRequest(b []byte) (Payload, error) {
+	return decode(b, requestDecoders)
+}
+
+func decode(b []byte, table map[string]func() Payload) (Payload, error) {
+	var env rawEnvelope
+	err := msgpack.Unmarshal(b, &env)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao deserializar mensagem: %v", err)
+	}
+
+	newPayload, ok := table[env.Service]
+	if !ok {
+		return nil, fmt.Errorf("serviço desconhecido: '%s'", env.Service)
+	}
+
+	var fields map[string]msgpack.RawMessage
+	err = msgpack.Unmarshal(env.Data, &fields)
+	if err != nil {
+		return nil, fmt.Errorf("campo data de '%s' inválido: %v", env.Service, err)
+	}
+
+	p := newPayload()
+	err = decodeFields(env.Service, fields, reflect.ValueOf(p).Elem())
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// decodeFields preenche a struct campo a campo para que o erro indique qual
+// campo está ausente ou tem o tipo errado.
+func decodeFields(service string, fields map[string]msgpack.RawMessage, v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			err := decodeFields(service, fields, v.Field(i))
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		name, opts, _ := strings.Cut(field.Tag.Get("msgpack"), ",")
+		if name == "-" {
+			// Campo local, fora do protocolo
+			continue
+		}
+		raw, ok := fields[name]
+		if !ok || bytes.Equal(raw, []byte{msgpcode.Nil}) {
+			if strings.Contains(opts, "omitempty") {
+				continue
+			}
+			return fmt.Errorf("campo obrigatório '%s' ausente em '%s'", name, service)
+		}
+
+		if field.Type.Kind() == reflect.Int64 {
+			// clock e timestamp chegam em qualquer representação numérica
+			var number interface{}
+			err := msgpack.Unmarshal(raw, &number)
+			n, ok := ClockValue(number)
+			if err != nil || !ok {
+				return fmt.Errorf("campo '%s' de '%s' com tipo inválido: %T", name, service, number)
+			}
+			v.Field(i).SetInt(n)
+			continue
+		}
+
+		if field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct {
+			// Listas de mensagens (histórico) passam pelas mesmas regras
+			err := decodeList(service, name, raw, v.Field(i))
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		err := msgpack.Unmarshal(raw, v.Field(i).Addr().Interface())
+		if err != nil {
+			return fmt.Errorf("campo '%s' de '%s' com tipo inválido: %v", name, service, err)
+		}
+	}
+	return nil
+}
+
+// decodeList decodifica cada elemento de uma lista de structs com
+// decodeFields.
+func decodeList(service, name string, raw msgpack.RawMessage, v reflect.Value) error {
+	var items []msgpack.RawMessage
+	err := msgpack.Unmarshal(raw, &items)
+	if err != nil {
+		return fmt.Errorf("campo '%s' de '%s' com tipo inválido: %v", name, service, err)
+	}
+
+	list := reflect.MakeSlice(v.Type(), len(items), len(items))
+	for i, item := range items {
+		var fields map[string]msgpack.RawMessage
+		err := msgpack.Unmarshal(item, &fields)
+		if err != nil {
+			return fmt.Errorf("item %d de '%s' em '%s' inválido: %v", i, name, service, err)
+		}
+		err = decodeFields(service, fields, list.Index(i))
+		if err != nil {
+			return fmt.Errorf("item %d de '%s': %w", i, name, err)
+		}
+	}
+	v.Set(list)
+	return nil
+}
+
+// DecodeMsgpack aceita wall e logical em qualquer representação numérica,
+// como clock e timestamp: o servidor em JavaScript pode repassar meta.hlc
+// com os números como float.
+func (h *HLC) DecodeMsgpack(d *msgpack.Decoder) error {
+	var fields map[string]interface{}
+	err := d.Decode(&fields)
+	if err != nil {
+		return fmt.Errorf("hlc inválido: %v", err)
+	}
+
+	*h = HLC{}
+	for name, target := range map[string]*int64{"wall": &h.Wall, "logical": &h.Logical} {
+		value, ok := fields[name]
+		if !ok || value == nil {
+			continue
+		}
+		n, ok := ClockValue(value)
+		if !ok {
+			return fmt.Errorf("campo '%s' do hlc com tipo inválido: %T", name, value)
+		}
+		*target = n
+	}
+	return nil
+}
+
+// DecodeMsgpack aceita os contadores em qualquer representação numérica,
+// pelo mesmo motivo de HLC.DecodeMsgpack.
+func (vc *VectorClock) DecodeMsgpack(d *msgpack.Decoder) error {
+	var fields map[string]interface{}
+	err := d.Decode(&fields)
+	if err != nil {
+		return fmt.Errorf("vc inválido: %v", err)
+	}
+	if fields == nil {
+		*vc = nil
+		return nil
+	}
+
+	decoded := make(VectorClock, len(fields))
+	for author, value := range fields {
+		n, ok := ClockValue(value)
+		if !ok {
+			return fmt.Errorf("contador de '%s' no vc com tipo inválido: %T", author, value)
+		}
+		decoded[author] = n
+	}
+	*vc = decoded
+	return nil
+}
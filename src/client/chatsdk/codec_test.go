@@ -0,0 +1,74 @@
+package chatsdk_test
+
+import (
+	"testing"
+
+	"chat-client/chatsdk"
+
+	msgpack "github.com/vmihailenco/msgpack/v5"
+)
+
+// TestDecodeMetaFloatClocks decodifica uma publicação cujo meta tem hlc e vc
+// com números em float, como um servidor JavaScript pode repassá-los.
+func TestDecodeMetaFloatClocks(t *testing.T) {
+	encoded, err := msgpack.Marshal(map[string]interface{}{
+		"service": chatsdk.ServicePublication,
+		"data": map[string]interface{}{
+			"user":      "alice",
+			"channel":   "geral",
+			"message":   "olá",
+			"timestamp": float64(1700000000000),
+			"clock":     float64(7),
+			"meta": map[string]interface{}{
+				"id":  "m1",
+				"hlc": map[string]interface{}{"wall": float64(1700000000000), "logical": float32(2)},
+				"vc":  map[string]interface{}{"alice": float64(3), "bob": uint8(1)},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("erro ao serializar: %v", err)
+	}
+
+	payload, err := chatsdk.Decode(encoded)
+	if err != nil {
+		t.Fatalf("erro ao decodificar: %v", err)
+	}
+	p := payload.(*chatsdk.Publication)
+	if p.Meta == nil || p.Meta.HLC == nil {
+		t.Fatalf("meta sem hlc: %+v", p.Meta)
+	}
+	if *p.Meta.HLC != (chatsdk.HLC{Wall: 1700000000000, Logical: 2}) {
+		t.Fatalf("hlc = %+v", *p.Meta.HLC)
+	}
+	if p.Meta.VC["alice"] != 3 || p.Meta.VC["bob"] != 1 || len(p.Meta.VC) != 2 {
+		t.Fatalf("vc = %v", p.Meta.VC)
+	}
+
+	// O formato produzido pelo próprio SDK continua decodificando
+	roundTrip, err := chatsdk.Encode(p)
+	if err != nil {
+		t.Fatalf("erro ao serializar: %v", err)
+	}
+	again, err := chatsdk.Decode(roundTrip)
+	if err != nil {
+		t.Fatalf("erro ao decodificar: %v", err)
+	}
+	if q := again.(*chatsdk.Publication); *q.Meta.HLC != *p.Meta.HLC || q.Meta.VC["alice"] != 3 {
+		t.Fatalf("ida e volta = %+v", q.Meta)
+	}
+}
+
+func TestDecodeMetaInvalidClock(t *testing.T) {
+	encoded, _ := msgpack.Marshal(map[string]interface{}{
+		"service": chatsdk.ServicePublication,
+		"data": map[string]interface{}{
+			"user": "alice", "channel": "geral", "message": "olá", "timestamp": 1, "clock": 1,
+			"meta": map[string]interface{}{"vc": map[string]interface{}{"alice": "três"}},
+		},
+	})
+	_, err := chatsdk.Decode(encoded)
+	if err == nil {
+		t.Fatalf("vc com contador em texto deveria falhar")
+	}
+}
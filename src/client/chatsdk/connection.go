@@ -0,0 +1,113 @@
+package chatsdk
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pebbe/zmq4"
+)
+
+// Pausa entre tentativas de recriar o socket SUB após um erro
+const reconnectDelay = time.Second
+
+// ConnectionState é o estado da assinatura no proxy, visto pelo Listen.
+type ConnectionState int
+
+const (
+	// StateConnected indica que mensagens ou heartbeats estão chegando
+	StateConnected ConnectionState = iota
+	// StateDisconnected indica erro no socket SUB ou silêncio além do
+	// prazo; publicações feitas a partir de Since podem ter sido perdidas
+	StateDisconnected
+	// StateReconnecting indica que o socket SUB foi recriado e aguarda a
+	// primeira mensagem
+	StateReconnecting
+)
+
+func (s ConnectionState) String() string {
+	switch s {
+	case StateConnected:
+		return "conectado"
+	case StateDisconnected:
+		return "desconectado"
+	case StateReconnecting:
+		return "reconectando"
+	}
+	return fmt.Sprintf("estado %d", int(s))
+}
+
+// ConnectionEvent é entregue a Handler.State a cada mudança de estado.
+type ConnectionEvent struct {
+	State ConnectionState
+	// Err é a causa da desconexão (ErrNoHeartbeat ou erro do socket) ou, em
+	// StateConnected, a falha ao recuperar o histórico (inclusive
+	// ErrBackfillTruncated)
+	Err error
+	// Since é o instante da última mensagem recebida antes da queda. Em
+	// StateConnected só é preenchido ao fim de uma queda; zerado, o evento
+	// vem de uma conferência periódica (WithGapCheck) que recuperou
+	// publicações ou falhou.
+	Since time.Time
+	// Recovered é o número de publicações perdidas que foram recuperadas
+	// pelo histórico e entregues antes deste evento. Mensagens privadas não
+	// são recuperadas.
+	Recovered int
+}
+
+// subMonitor acompanha a atividade do socket SUB. Só é usado pela goroutine
+// de Listen.
+type subMonitor struct {
+	idleTimeout  time.Duration
+	lastActivity time.Time
+	// Silêncio só indica queda depois que o servidor mostrou que publica
+	// heartbeats (versões anteriores de main.js não publicam)
+	heartbeats bool
+	connected  bool
+	// Última atividade antes da queda atual (zero se não há queda)
+	outage time.Time
+}
+
+func (m *subMonitor) silent(now time.Time) bool {
+	return m.heartbeats && m.idleTimeout > 0 && now.Sub(m.lastActivity) > m.idleTimeout
+}
+
+// resetSubSocket descarta o socket SUB e cria outro com o conjunto atual de
+// assinaturas. Só deve ser chamada pela goroutine de Listen.
+func (c *Client) resetSubSocket() error {
+	if c.subSocket != nil {
+		c.subSocket.SetLinger(0)
+		c.subSocket.Close()
+		c.subSocket = nil
+	}
+
+	subSocket, err := c.zmqContext.NewSocket(zmq4.SUB)
+	if err != nil {
+		return fmt.Errorf("erro ao criar socket SUB: %v", err)
+	}
+	err = subSocket.Connect(c.opts.proxyEndpoint)
+	if err != nil {
+		subSocket.Close()
+		return fmt.Errorf("erro ao conectar ao proxy: %v", err)
+	}
+
+	// O novo socket recebe todas as assinaturas, então as mudanças
+	// pendentes já estão incluídas
+	c.subMu.Lock()
+	topics := []string{HeartbeatTopic}
+	for topic := range c.subscriptions {
+		topics = append(topics, topic)
+	}
+	c.pendingSubs = nil
+	c.subMu.Unlock()
+
+	for _, topic := range topics {
+		err = subSocket.SetSubscribe(topic)
+		if err != nil {
+			subSocket.Close()
+			return fmt.Errorf("erro ao configurar subscription '%s': %v", topic, err)
+		}
+	}
+
+	c.subSocket = subSocket
+	return nil
+}
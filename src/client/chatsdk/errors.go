@@ -0,0 +1,53 @@
+package chatsdk
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrTimeout indica que nenhuma réplica respondeu dentro da RetryPolicy.
+	ErrTimeout = errors.New("tempo esgotado aguardando resposta do servidor")
+
+	// ErrClosed é retornado por requisições feitas depois de Close.
+	ErrClosed = errors.New("cliente encerrado")
+
+	// ErrNoHeartbeat indica que a assinatura ficou em silêncio além do
+	// prazo de WithIdleTimeout depois de já ter recebido heartbeats.
+	ErrNoHeartbeat = errors.New("nenhum heartbeat recebido do proxy")
+
+	// ErrBackfillTruncated indica que o histórico de um canal tinha mais
+	// publicações a conferir que WithBackfillLimit; as mais antigas podem
+	// ter sido perdidas.
+	ErrBackfillTruncated = errors.New("histórico truncado pelo limite de recuperação")
+
+	// errAttemptTimeout marca o fim do prazo de uma única tentativa
+	errAttemptTimeout = errors.New("tentativa sem resposta")
+)
+
+// Prefixos usados nas mensagens de erro de cada serviço
+var errorPrefixes = map[string]string{
+	ServiceLogin:     "erro no login",
+	ServiceUsers:     "erro ao listar usuários",
+	ServiceChannel:   "erro ao criar canal",
+	ServiceChannels:  "erro ao listar canais",
+	ServicePublish:   "erro ao publicar",
+	ServiceMessage:   "erro ao enviar mensagem",
+	ServiceHistory:   "erro ao buscar histórico",
+	ServiceDMHistory: "erro ao buscar histórico de mensagens privadas",
+}
+
+// ServerError indica que o servidor processou a requisição e respondeu com
+// status "erro" (ou com o serviço "error").
+type ServerError struct {
+	Service     string
+	Description string
+}
+
+func (e *ServerError) Error() string {
+	prefix, ok := errorPrefixes[e.Service]
+	if !ok {
+		prefix = "erro no servidor"
+	}
+	return fmt.Sprintf("%s: %s", prefix, e.Description)
+}
@@ -0,0 +1,108 @@
+package chatsdk
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// HLC é um carimbo de relógio lógico híbrido: o maior tempo físico conhecido
+// (em milissegundos) e um contador que ordena os eventos desse milissegundo.
+// Segue a causalidade como o relógio de Lamport e fica próximo do horário
+// real, mesmo com relógios dessincronizados.
+type HLC struct {
+	Wall    int64 `msgpack:"wall" json:"wall"`
+	Logical int64 `msgpack:"logical" json:"logical"`
+}
+
+// Before indica se h ordena antes de other.
+func (h HLC) Before(other HLC) bool {
+	if h.Wall != other.Wall {
+		return h.Wall < other.Wall
+	}
+	return h.Logical < other.Logical
+}
+
+func (h HLC) String() string {
+	return fmt.Sprintf("%s+%d", time.UnixMilli(h.Wall).Format("15:04:05.000"), h.Logical)
+}
+
+// HybridClock é o relógio lógico híbrido do processo. É seguro para uso
+// concorrente.
+type HybridClock struct {
+	mu   sync.Mutex
+	last HLC
+}
+
+// Now avança o relógio para um envio e retorna o carimbo do evento.
+func (c *HybridClock) Now() HLC {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	wall := time.Now().UnixMilli()
+	if wall > c.last.Wall {
+		c.last = HLC{Wall: wall}
+	} else {
+		c.last.Logical++
+	}
+	return c.last
+}
+
+// Update incorpora o carimbo recebido em uma mensagem e retorna o novo valor.
+func (c *HybridClock) Update(received HLC) HLC {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	wall := max(time.Now().UnixMilli(), c.last.Wall, received.Wall)
+	switch {
+	case wall == c.last.Wall && wall == received.Wall:
+		c.last.Logical = max(c.last.Logical, received.Logical) + 1
+	case wall == c.last.Wall:
+		c.last.Logical++
+	case wall == received.Wall:
+		c.last = HLC{Wall: wall, Logical: received.Logical + 1}
+	default:
+		c.last = HLC{Wall: wall}
+	}
+	return c.last
+}
+
+// Value retorna o valor atual sem alterá-lo.
+func (c *HybridClock) Value() HLC {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.last
+}
+
+// mergeHLC incorpora o relógio híbrido de uma publicação ou mensagem
+// privada recebida.
+func (c *Client) mergeHLC(p Payload) {
+	var meta *Meta
+	switch m := p.(type) {
+	case *Publication:
+		meta = m.Meta
+	case *PrivateMessage:
+		meta = m.Meta
+	}
+	if meta != nil && meta.HLC != nil {
+		c.hlc.Update(*meta.HLC)
+	}
+}
+
+// HLC retorna o carimbo híbrido da publicação ou, se o remetente não o
+// enviou, o timestamp dela.
+func (p Publication) HLC() HLC {
+	if p.Meta != nil && p.Meta.HLC != nil {
+		return *p.Meta.HLC
+	}
+	return HLC{Wall: p.Timestamp}
+}
+
+// HLC retorna o carimbo híbrido da mensagem ou, se o remetente não o enviou,
+// o timestamp dela.
+func (m PrivateMessage) HLC() HLC {
+	if m.Meta != nil && m.Meta.HLC != nil {
+		return *m.Meta.HLC
+	}
+	return HLC{Wall: m.Timestamp}
+}
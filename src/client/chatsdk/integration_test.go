@@ -0,0 +1,243 @@
+package chatsdk_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"chat-client/chatsdk"
+)
+
+// states retorna os eventos entregues a Handler.State a partir do n-ésimo.
+func (in *inbox) states(n int) []chatsdk.ConnectionEvent {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return append([]chatsdk.ConnectionEvent(nil), in.events[n:]...)
+}
+
+// findState retorna o primeiro evento com o estado a partir do n-ésimo.
+func (in *inbox) findState(n int, state chatsdk.ConnectionState) (chatsdk.ConnectionEvent, bool) {
+	for _, event := range in.states(n) {
+		if event.State == state {
+			return event, true
+		}
+	}
+	return chatsdk.ConnectionEvent{}, false
+}
+
+func TestLoginPublishAndMessage(t *testing.T) {
+	s := newServer(t)
+	client := newClient(t, s)
+	ctx := context.Background()
+
+	login, err := client.Login(ctx, "alice")
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if login.Status != chatsdk.StatusSuccess {
+		t.Fatalf("status do login = %q", login.Status)
+	}
+	if client.Username() != "alice" {
+		t.Fatalf("usuário = %q, esperado alice", client.Username())
+	}
+	if users := s.Users(); len(users) != 1 || users[0] != "alice" {
+		t.Fatalf("usuários no servidor = %v", users)
+	}
+
+	_, err = client.CreateChannel(ctx, "geral")
+	if err != nil {
+		t.Fatalf("criar canal: %v", err)
+	}
+	published, err := client.PublishMessage(ctx, "geral", "olá")
+	if err != nil {
+		t.Fatalf("publicar: %v", err)
+	}
+	if published.Status != chatsdk.StatusOK {
+		t.Fatalf("status da publicação = %q", published.Status)
+	}
+	publications := s.Publications()
+	if len(publications) != 1 || publications[0].User != "alice" || publications[0].Channel != "geral" || publications[0].Message != "olá" {
+		t.Fatalf("publicações no servidor = %+v", publications)
+	}
+
+	s.AddUser("bob")
+	sent, err := client.SendPrivateMessage(ctx, "bob", "oi, bob")
+	if err != nil {
+		t.Fatalf("mensagem privada: %v", err)
+	}
+	if sent.Status != chatsdk.StatusOK {
+		t.Fatalf("status da mensagem = %q", sent.Status)
+	}
+	messages := s.Messages()
+	if len(messages) != 1 || messages[0].Src != "alice" || messages[0].Dst != "bob" || messages[0].Message != "oi, bob" {
+		t.Fatalf("mensagens no servidor = %+v", messages)
+	}
+
+	// As respostas avançam o relógio do cliente
+	if client.Clock() < sent.Clock {
+		t.Fatalf("relógio do cliente %d menor que o da resposta %d", client.Clock(), sent.Clock)
+	}
+}
+
+// TestRetryReusesRequestID descarta a primeira resposta: o reenvio leva o
+// mesmo request_id e o servidor não publica duas vezes.
+func TestRetryReusesRequestID(t *testing.T) {
+	s := newServer(t)
+	s.AddChannel("geral")
+	client := newClient(t, s)
+	ctx := context.Background()
+	_, err := client.Login(ctx, "alice")
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+
+	s.DropNext(1)
+	response, err := client.PublishMessage(ctx, "geral", "só uma vez")
+	if err != nil {
+		t.Fatalf("publicar: %v", err)
+	}
+	if !response.Duplicate {
+		t.Fatalf("resposta do reenvio não marcada como duplicata")
+	}
+
+	var ids []string
+	for _, request := range s.Requests() {
+		if publish, ok := request.(*chatsdk.PublishRequest); ok {
+			ids = append(ids, publish.RequestID)
+		}
+	}
+	if len(ids) != 2 {
+		t.Fatalf("%d requisições publish, esperadas 2", len(ids))
+	}
+	if ids[0] == "" || ids[0] != ids[1] {
+		t.Fatalf("request_id dos envios = %q e %q", ids[0], ids[1])
+	}
+	if response.RequestID != ids[0] {
+		t.Fatalf("request_id da resposta = %q, esperado %q", response.RequestID, ids[0])
+	}
+	if n := len(s.Publications()); n != 1 {
+		t.Fatalf("%d publicações no servidor, esperada 1", n)
+	}
+}
+
+func TestPushedPublicationReachesHandler(t *testing.T) {
+	s := newServer(t)
+	client := newClient(t, s)
+	received := &inbox{}
+	client.Subscribe("geral")
+	client.Listen(received.handler())
+	subscribed(t, s, received, "geral")
+
+	// Publicações de tópicos não assinados não chegam ao handler
+	s.Publish("outro", &chatsdk.Publication{Channel: "outro", User: "bob", Message: "ignorada"})
+	err := s.Publish("geral", &chatsdk.Publication{Channel: "geral", User: "bob", Message: "do servidor"})
+	if err != nil {
+		t.Fatalf("publicar: %v", err)
+	}
+	waitFor(t, "publicação", func() bool {
+		n, _ := received.counts()
+		return n > 0
+	})
+	time.Sleep(50 * time.Millisecond)
+
+	received.mu.Lock()
+	defer received.mu.Unlock()
+	if len(received.publications) != 1 {
+		t.Fatalf("%d publicações entregues, esperada 1: %+v", len(received.publications), received.publications)
+	}
+	p := received.publications[0]
+	if p.Channel != "geral" || p.User != "bob" || p.Message != "do servidor" {
+		t.Fatalf("publicação entregue = %+v", p)
+	}
+	if p.Clock == 0 || p.Timestamp == 0 {
+		t.Fatalf("publicação sem clock ou timestamp: %+v", p)
+	}
+}
+
+// TestHeartbeatSilenceReconnects silencia o proxy depois de um heartbeat: o
+// cliente deve passar por desconectado e reconectando e, quando o proxy
+// volta, ficar conectado recuperando pelo histórico o que foi publicado
+// durante a queda.
+func TestHeartbeatSilenceReconnects(t *testing.T) {
+	s := newServer(t)
+	s.AddChannel("geral")
+	client := newClient(t, s, chatsdk.WithIdleTimeout(200*time.Millisecond))
+	received := &inbox{}
+	client.Subscribe("geral")
+	client.Listen(received.handler())
+	subscribed(t, s, received, "geral")
+
+	err := s.Heartbeat()
+	if err != nil {
+		t.Fatalf("heartbeat: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	// Ignora o evento da primeira conexão
+	start := len(received.states(0))
+	s.Mute(true)
+
+	waitFor(t, "desconexão", func() bool {
+		_, ok := received.findState(start, chatsdk.StateDisconnected)
+		return ok
+	})
+	event, _ := received.findState(start, chatsdk.StateDisconnected)
+	if !errors.Is(event.Err, chatsdk.ErrNoHeartbeat) {
+		t.Fatalf("causa da desconexão = %v", event.Err)
+	}
+	if event.Since.IsZero() {
+		t.Fatalf("desconexão sem Since")
+	}
+	waitFor(t, "reconexão", func() bool {
+		_, ok := received.findState(start, chatsdk.StateReconnecting)
+		return ok
+	})
+
+	// Publicada durante a queda: só chega pelo histórico
+	sender := newClient(t, s)
+	_, err = sender.Login(context.Background(), "bob")
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	_, err = sender.PublishMessage(context.Background(), "geral", "durante a queda")
+	if err != nil {
+		t.Fatalf("publicar: %v", err)
+	}
+
+	s.Mute(false)
+	waitFor(t, "conexão", func() bool {
+		s.Heartbeat()
+		_, ok := received.findState(start, chatsdk.StateConnected)
+		return ok
+	})
+	event, _ = received.findState(start, chatsdk.StateConnected)
+	if event.Err != nil {
+		t.Fatalf("erro ao recuperar histórico: %v", event.Err)
+	}
+	if event.Recovered != 1 {
+		t.Fatalf("%d publicações recuperadas, esperada 1", event.Recovered)
+	}
+
+	received.mu.Lock()
+	defer received.mu.Unlock()
+	if len(received.publications) != 1 || received.publications[0].Message != "durante a queda" {
+		t.Fatalf("publicações entregues = %+v", received.publications)
+	}
+
+	// A ordem dos estados é desconectado, reconectando, conectado
+	var order []chatsdk.ConnectionState
+	for _, e := range received.events[start:] {
+		if len(order) == 0 || order[len(order)-1] != e.State {
+			order = append(order, e.State)
+		}
+	}
+	want := []chatsdk.ConnectionState{chatsdk.StateDisconnected, chatsdk.StateReconnecting, chatsdk.StateConnected}
+	if len(order) != len(want) {
+		t.Fatalf("estados = %v, esperados %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("estados = %v, esperados %v", order, want)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package chatsdk
+
+import (
+	"log"
+	"time"
+
+	"github.com/pebbe/zmq4"
+)
+
+// Handler recebe as mensagens entregues pelo proxy. Campos nulos são ignorados.
+type Handler struct {
+	Publication    func(Publication)
+	PrivateMessage func(PrivateMessage)
+	// State é chamado quando a assinatura cai ou volta a receber mensagens
+	State func(ConnectionEvent)
+}
+
+// Listen inicia uma goroutine que recebe as mensagens dos tópicos assinados
+// e as repassa ao handler. Apenas a primeira chamada tem efeito; a goroutine
+// termina em Close.
+//
+// Se o socket SUB falha, ou fica em silêncio além de WithIdleTimeout depois
+// de já ter recebido heartbeats, ele é recriado com as mesmas assinaturas.
+func (c *Client) Listen(h Handler) {
+	c.listenOnce.Do(func() {
+		go c.listen(h)
+	})
+}
+
+func (c *Client) listen(h Handler) {
+	defer close(c.listenDone)
+
+	monitor := &subMonitor{
+		idleTimeout:  c.opts.idleTimeout,
+		lastActivity: time.Now(),
+	}
+	gaps := newGapTracker(c.opts.gapCheck)
+	emit := func(event ConnectionEvent) {
+		if h.State != nil {
+			h.State(event)
+		}
+	}
+
+	// Os heartbeats chegam pelo mesmo socket das mensagens
+	err := c.subSocket.SetSubscribe(HeartbeatTopic)
+	if err != nil {
+		log.Printf("Erro ao assinar heartbeats: %v", err)
+	}
+	poller := zmq4.NewPoller()
+	poller.Add(c.subSocket, zmq4.POLLIN)
+
+	// reconnect marca a queda (uma vez por queda) e recria o socket SUB
+	reconnect := func(cause error) {
+		if monitor.outage.IsZero() {
+			monitor.outage = monitor.lastActivity
+			monitor.connected = false
+			emit(ConnectionEvent{State: StateDisconnected, Err: cause, Since: monitor.outage})
+		}
+
+		err := c.resetSubSocket()
+		if err != nil {
+			log.Printf("Erro ao reconectar ao proxy: %v", err)
+			select {
+			case <-c.done:
+			case <-time.After(reconnectDelay):
+			}
+			return
+		}
+		poller = zmq4.NewPoller()
+		poller.Add(c.subSocket, zmq4.POLLIN)
+		monitor.lastActivity = time.Now()
+		emit(ConnectionEvent{State: StateReconnecting, Since: monitor.outage})
+	}
+
+	for {
+		select {
+		case <-c.done:
+			return
+		default:
+		}
+
+		if c.subSocket == nil {
+			reconnect(nil)
+			continue
+		}
+
+		err := c.applySubscriptions()
+		if err != nil {
+			log.Printf("Erro ao atualizar subscriptions: %v", err)
+		}
+
+		c.releaseExpired(h)
+
+		polled, err := poller.Poll(pollSlice)
+		if err != nil {
+			log.Printf("Erro ao aguardar mensagens: %v", err)
+			reconnect(err)
+			continue
+		}
+		if monitor.connected && gaps.due(time.Now()) {
+			// Conferência sem queda: só avisa se algo foi recuperado
+			recovered, err := c.backfill(h, gaps, time.Now())
+			if recovered > 0 || err != nil {
+				emit(ConnectionEvent{State: StateConnected, Err: err, Recovered: recovered})
+			}
+		}
+
+		if len(polled) == 0 {
+			if monitor.silent(time.Now()) {
+				reconnect(ErrNoHeartbeat)
+			}
+			continue
+		}
+
+		// Receber tópico e mensagem
+		frames, err := c.subSocket.RecvMessageBytes(0)
+		if err != nil {
+			log.Printf("Erro ao receber mensagem: %v", err)
+			reconnect(err)
+			continue
+		}
+
+		monitor.lastActivity = time.Now()
+		message := c.receivedPayload(frames)
+
+		// A publicação que chegou é registrada antes da recuperação, para
+		// não ser entregue também pelo histórico
+		live := false
+		if p, ok := message.(*Publication); ok {
+			live = gaps.observe(p)
+		}
+
+		if !monitor.connected {
+			// Ao fim de uma queda, buscar o que foi publicado nesse intervalo
+			event := ConnectionEvent{State: StateConnected, Since: monitor.outage}
+			if !monitor.outage.IsZero() {
+				event.Recovered, event.Err = c.backfill(h, gaps, monitor.outage.Add(-backfillMargin))
+			}
+			monitor.connected = true
+			monitor.outage = time.Time{}
+			emit(event)
+		}
+
+		if message == nil {
+			continue
+		}
+
+		// Atualizar relógios lógicos
+		c.clock.Merge(message.header().Clock)
+		c.mergeHLC(message)
+
+		// Processar mensagem baseada no serviço
+		switch m := message.(type) {
+		case *Heartbeat:
+			monitor.heartbeats = true
+		case *Publication:
+			if live {
+				c.deliverPublication(h, *m)
+			}
+		case *PrivateMessage:
+			if h.PrivateMessage != nil && m.Dst == c.Username() {
+				h.PrivateMessage(*m)
+			}
+		}
+	}
+}
+
+// receivedPayload decodifica a mensagem recebida no socket SUB, ou retorna
+// nil se ela deve ser ignorada.
+func (c *Client) receivedPayload(frames [][]byte) Payload {
+	if len(frames) != 2 {
+		log.Printf("Mensagem com %d partes ignorada", len(frames))
+		return nil
+	}
+	topic := string(frames[0])
+	if topic != HeartbeatTopic && !c.isSubscribed(topic) {
+		return nil
+	}
+
+	// Deserializar mensagem
+	message, err := Decode(frames[1])
+	if err != nil {
+		log.Printf("Erro ao deserializar mensagem: %v", err)
+		return nil
+	}
+	return message
+}
@@ -0,0 +1,141 @@
+package chatsdk
+
+import "time"
+
+// Endereços padrão usados dentro do docker-compose
+const (
+	DefaultBrokerEndpoint = "tcp://broker:5555"
+	DefaultProxyEndpoint  = "tcp://proxy:5558"
+)
+
+// RetryPolicy controla as tentativas de cada requisição (Lazy Pirate).
+type RetryPolicy struct {
+	// Timeout é a espera máxima pela resposta em cada tentativa
+	Timeout time.Duration
+	// Attempts é o número total de envios antes de desistir
+	Attempts int
+	// Backoff é a pausa entre uma tentativa sem resposta e a próxima
+	Backoff time.Duration
+}
+
+// DefaultRetryPolicy cobre a troca de primary entre as réplicas do servidor.
+var DefaultRetryPolicy = RetryPolicy{
+	Timeout:  2500 * time.Millisecond,
+	Attempts: 3,
+	Backoff:  500 * time.Millisecond,
+}
+
+// DefaultIdleTimeout é o silêncio máximo no socket SUB antes de recriá-lo.
+// Os servidores publicam heartbeats a cada 3s.
+const DefaultIdleTimeout = 10 * time.Second
+
+// DefaultBackfillLimit é o máximo de publicações conferidas por canal a
+// cada recuperação pelo histórico.
+const DefaultBackfillLimit = 1000
+
+// DefaultGapCheck é o intervalo entre as conferências periódicas com o
+// histórico, que recuperam publicações descartadas sem queda da assinatura.
+const DefaultGapCheck = time.Minute
+
+// DefaultCausalWait é a espera máxima de uma publicação pelas que a
+// precedem causalmente, com WithCausalOrder.
+const DefaultCausalWait = 2 * time.Second
+
+type options struct {
+	brokerEndpoint string
+	proxyEndpoint  string
+	username       string
+	retry          RetryPolicy
+	idleTimeout    time.Duration
+	backfillLimit  int
+	gapCheck       time.Duration
+	causalWait     time.Duration
+}
+
+func defaultOptions() options {
+	return options{
+		brokerEndpoint: DefaultBrokerEndpoint,
+		proxyEndpoint:  DefaultProxyEndpoint,
+		retry:          DefaultRetryPolicy,
+		idleTimeout:    DefaultIdleTimeout,
+		backfillLimit:  DefaultBackfillLimit,
+		gapCheck:       DefaultGapCheck,
+	}
+}
+
+// Option configura um Client criado por New.
+type Option func(*options)
+
+// WithBroker define o endereço do broker (socket REQ).
+func WithBroker(endpoint string) Option {
+	return func(o *options) {
+		o.brokerEndpoint = endpoint
+	}
+}
+
+// WithProxy define o endereço do proxy (socket SUB).
+func WithProxy(endpoint string) Option {
+	return func(o *options) {
+		o.proxyEndpoint = endpoint
+	}
+}
+
+// WithUsername define o nome usado por Login quando nenhum nome é informado.
+func WithUsername(username string) Option {
+	return func(o *options) {
+		o.username = username
+	}
+}
+
+// WithRetryPolicy substitui a DefaultRetryPolicy. Campos zerados mantêm o
+// valor padrão.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(o *options) {
+		if policy.Timeout > 0 {
+			o.retry.Timeout = policy.Timeout
+		}
+		if policy.Attempts > 0 {
+			o.retry.Attempts = policy.Attempts
+		}
+		if policy.Backoff > 0 {
+			o.retry.Backoff = policy.Backoff
+		}
+	}
+}
+
+// WithIdleTimeout define o silêncio máximo no socket SUB, depois do primeiro
+// heartbeat, antes de recriar o socket. Zero desativa a detecção.
+func WithIdleTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.idleTimeout = timeout
+	}
+}
+
+// WithBackfillLimit define quantas publicações por canal são buscadas no
+// histórico, em páginas, a cada recuperação (depois de uma queda ou numa
+// conferência periódica). Zero desativa a recuperação.
+func WithBackfillLimit(limit int) Option {
+	return func(o *options) {
+		o.backfillLimit = limit
+	}
+}
+
+// WithGapCheck define de quanto em quanto tempo Listen confere os canais
+// assinados com o histórico mesmo sem queda da assinatura, para recuperar
+// publicações descartadas pelo socket SUB (fila cheia). Zero desativa.
+func WithGapCheck(interval time.Duration) Option {
+	return func(o *options) {
+		o.gapCheck = interval
+	}
+}
+
+// WithCausalOrder ativa a entrega causal das publicações: cada publicação
+// leva em meta.vc um relógio vetorial do canal, e Listen segura as que
+// chegam antes das publicações de que dependem (uma resposta antes da
+// pergunta, por exemplo) por até maxWait. Publicações de clientes sem a
+// opção são entregues na ordem de chegada. Zero desativa.
+func WithCausalOrder(maxWait time.Duration) Option {
+	return func(o *options) {
+		o.causalWait = maxWait
+	}
+}
@@ -0,0 +1,310 @@
+package chatsdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Espera entre reenvios da fila de saída, dobrada a cada falha
+const (
+	outboxMinBackoff = time.Second
+	outboxMaxBackoff = 30 * time.Second
+)
+
+// OutboxItem é uma publicação ou mensagem privada aguardando envio.
+type OutboxItem struct {
+	// request_id da publicação ou mensagem, repetido em todos os reenvios,
+	// inclusive em execuções seguintes
+	Key string `json:"key"`
+	// ServicePublish ou ServiceMessage
+	Service string `json:"service"`
+	User    string `json:"user"`
+	// Canal ou destinatário
+	Target    string    `json:"target"`
+	Message   string    `json:"message"`
+	Created   time.Time `json:"created"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error,omitempty"`
+	// Relógios do envio original (Meta.HLC e Meta.VC), repetidos nos
+	// reenvios
+	Meta *Meta `json:"meta,omitempty"`
+}
+
+// OutboxStore persiste os itens da fila entre execuções.
+type OutboxStore interface {
+	SaveOutbox(item OutboxItem) error
+	DeleteOutbox(key string) error
+	LoadOutbox() ([]OutboxItem, error)
+}
+
+// OutboxHandler é notificado, pela goroutine da fila, do destino dos itens
+// que ficaram pendentes. Campos nil são ignorados.
+type OutboxHandler struct {
+	// O item foi aceito pelo servidor
+	Sent func(OutboxItem)
+	// O servidor recusou o item, que foi descartado
+	Failed func(OutboxItem, error)
+}
+
+// Outbox envia publicações e mensagens privadas pelo Client e, quando o
+// broker não responde, guarda-as e reenvia em ordem, com espera crescente.
+// Cada item mantém o mesmo request_id em todos os reenvios, para que um
+// reenvio depois de uma resposta perdida não seja publicado duas vezes.
+type Outbox struct {
+	client  *Client
+	store   OutboxStore
+	handler OutboxHandler
+
+	mu      sync.Mutex
+	pending []OutboxItem
+
+	wake   chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewOutbox cria a fila e retoma os itens guardados em store, que pode ser
+// nil para uma fila apenas em memória.
+func NewOutbox(c *Client, store OutboxStore, h OutboxHandler) (*Outbox, error) {
+	var pending []OutboxItem
+	if store != nil {
+		var err error
+		pending, err = store.LoadOutbox()
+		if err != nil {
+			return nil, fmt.Errorf("erro ao carregar fila de saída: %v", err)
+		}
+		sort.SliceStable(pending, func(i, j int) bool {
+			return pending[i].Created.Before(pending[j].Created)
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	o := &Outbox{
+		client:  c,
+		store:   store,
+		handler: h,
+		pending: pending,
+		wake:    make(chan struct{}, 1),
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	go o.run()
+	return o, nil
+}
+
+// Close para os reenvios. Os itens pendentes continuam no OutboxStore.
+func (o *Outbox) Close() {
+	o.cancel()
+	<-o.done
+}
+
+// Pending retorna os itens aguardando envio, do mais antigo ao mais novo.
+func (o *Outbox) Pending() []OutboxItem {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]OutboxItem(nil), o.pending...)
+}
+
+// Wake antecipa o próximo reenvio, por exemplo quando a conexão volta.
+func (o *Outbox) Wake() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Publish publica a mensagem no canal e retorna o cabeçalho da resposta do
+// servidor. Se o broker não responder, a mensagem fica na fila, queued é
+// true e o cabeçalho vem zerado; erros do servidor são retornados.
+func (o *Outbox) Publish(ctx context.Context, channel, message string) (header Header, queued bool, err error) {
+	return o.submit(ctx, ServicePublish, channel, message)
+}
+
+// SendPrivate envia a mensagem privada com as mesmas regras de Publish.
+func (o *Outbox) SendPrivate(ctx context.Context, destUser, message string) (header Header, queued bool, err error) {
+	return o.submit(ctx, ServiceMessage, destUser, message)
+}
+
+func (o *Outbox) submit(ctx context.Context, service, target, message string) (Header, bool, error) {
+	key, err := newRequestID()
+	if err != nil {
+		return Header{}, false, err
+	}
+	item := OutboxItem{
+		Key:     key,
+		Service: service,
+		User:    o.client.Username(),
+		Target:  target,
+		Message: message,
+		Created: time.Now(),
+	}
+	if service == ServicePublish {
+		item.Meta = o.client.stampPublication(target, item.User, nil)
+	} else {
+		item.Meta = o.client.stampMeta(nil)
+	}
+
+	// Com itens na frente, enviar agora passaria à frente deles
+	o.mu.Lock()
+	empty := len(o.pending) == 0
+	o.mu.Unlock()
+
+	if empty {
+		header, err := o.send(ctx, item)
+		if !retryable(err) {
+			return header, false, err
+		}
+		item.Attempts = 1
+		item.LastError = err.Error()
+	}
+
+	err = o.enqueue(item)
+	if err != nil {
+		return Header{}, false, err
+	}
+	return Header{}, true, nil
+}
+
+// retryable indica se vale a pena reenviar: o servidor não chegou a
+// responder. Recusas do servidor e cancelamentos são definitivos.
+func retryable(err error) bool {
+	var serverErr *ServerError
+	return err != nil && !errors.As(err, &serverErr) && !errors.Is(err, ErrClosed) &&
+		!errors.Is(err, context.Canceled)
+}
+
+func (o *Outbox) enqueue(item OutboxItem) error {
+	if o.store != nil {
+		err := o.store.SaveOutbox(item)
+		if err != nil {
+			return fmt.Errorf("erro ao guardar mensagem na fila de saída: %v", err)
+		}
+	}
+
+	o.mu.Lock()
+	o.pending = append(o.pending, item)
+	o.mu.Unlock()
+	o.Wake()
+	return nil
+}
+
+// send envia o item e retorna o cabeçalho da resposta.
+func (o *Outbox) send(ctx context.Context, item OutboxItem) (Header, error) {
+	switch item.Service {
+	case ServicePublish:
+		response, err := call[*PublishResponse](ctx, o.client, &PublishRequest{
+			User:    item.User,
+			Channel: item.Target,
+			Message: item.Message,
+			Meta:    item.Meta,
+			Header:  Header{RequestID: item.Key},
+		})
+		o.client.settlePublication(item.Target, item.User, item.Meta, err)
+		if err != nil {
+			return Header{}, err
+		}
+		return response.Header, nil
+	case ServiceMessage:
+		response, err := call[*MessageResponse](ctx, o.client, &MessageRequest{
+			Src:     item.User,
+			Dst:     item.Target,
+			Message: item.Message,
+			Meta:    item.Meta,
+			Header:  Header{RequestID: item.Key},
+		})
+		if err != nil {
+			return Header{}, err
+		}
+		return response.Header, nil
+	}
+	return Header{}, fmt.Errorf("serviço inválido na fila de saída: '%s'", item.Service)
+}
+
+// run reenvia o item mais antigo até que seja aceito ou recusado, esperando
+// entre as tentativas; os seguintes aguardam para manter a ordem.
+func (o *Outbox) run() {
+	defer close(o.done)
+
+	backoff := outboxMinBackoff
+	for {
+		o.mu.Lock()
+		var head *OutboxItem
+		if len(o.pending) > 0 {
+			item := o.pending[0]
+			head = &item
+		}
+		o.mu.Unlock()
+
+		if head == nil {
+			select {
+			case <-o.ctx.Done():
+				return
+			case <-o.wake:
+			}
+			continue
+		}
+
+		// O primeiro envio de um item novo já falhou em submit
+		if head.Attempts > 0 {
+			select {
+			case <-o.ctx.Done():
+				return
+			case <-o.wake:
+			case <-time.After(backoff):
+			}
+		}
+
+		_, err := o.send(o.ctx, *head)
+		if o.ctx.Err() != nil {
+			return
+		}
+		if retryable(err) {
+			head.Attempts++
+			head.LastError = err.Error()
+			o.update(*head)
+			backoff = min(2*backoff, outboxMaxBackoff)
+			continue
+		}
+
+		backoff = outboxMinBackoff
+		o.remove(head.Key)
+		if err != nil {
+			if o.handler.Failed != nil {
+				o.handler.Failed(*head, err)
+			}
+		} else if o.handler.Sent != nil {
+			o.handler.Sent(*head)
+		}
+	}
+}
+
+func (o *Outbox) update(item OutboxItem) {
+	o.mu.Lock()
+	if len(o.pending) > 0 && o.pending[0].Key == item.Key {
+		o.pending[0] = item
+	}
+	o.mu.Unlock()
+
+	if o.store != nil {
+		// Falhar aqui só perde o contador de tentativas
+		o.store.SaveOutbox(item)
+	}
+}
+
+func (o *Outbox) remove(key string) {
+	o.mu.Lock()
+	if len(o.pending) > 0 && o.pending[0].Key == key {
+		o.pending = o.pending[1:]
+	}
+	o.mu.Unlock()
+
+	if o.store != nil {
+		o.store.DeleteOutbox(key)
+	}
+}
@@ -0,0 +1,247 @@
+package chatsdk
+
+// Serviços do protocolo (campo service do envelope)
+const (
+	ServiceLogin          = "login"
+	ServiceUsers          = "users"
+	ServiceChannel        = "channel"
+	ServiceChannels       = "channels"
+	ServicePublish        = "publish"
+	ServiceMessage        = "message"
+	ServiceHistory        = "history"
+	ServiceDMHistory      = "dm_history"
+	ServicePublication    = "publication"
+	ServicePrivateMessage = "private_message"
+	ServiceHeartbeat      = "heartbeat"
+	ServiceError          = "error"
+)
+
+// Tópico em que os servidores publicam Heartbeat periodicamente
+const HeartbeatTopic = "_heartbeat"
+
+// Valores do campo status das respostas
+const (
+	StatusSuccess = "sucesso"
+	StatusOK      = "OK"
+	StatusError   = "erro"
+)
+
+// Header contém os campos presentes em todas as mensagens. Campos sem
+// omitempty são obrigatórios na decodificação.
+type Header struct {
+	Timestamp int64 `msgpack:"timestamp"`
+	Clock     int64 `msgpack:"clock"`
+	// Identifica a requisição, inclusive nos reenvios; o servidor o repete
+	// na resposta e nos itens do histórico que ela criou
+	RequestID string `msgpack:"request_id,omitempty"`
+	// A resposta é a de uma requisição com o mesmo RequestID já processada,
+	// repetida sem refazer o efeito
+	Duplicate bool `msgpack:"duplicate,omitempty"`
+}
+
+func (h *Header) header() *Header {
+	return h
+}
+
+// Payload é o conteúdo do campo data de uma mensagem do protocolo.
+type Payload interface {
+	Service() string
+	header() *Header
+}
+
+// Meta carrega dados do cliente que o servidor guarda e repassa sem
+// interpretar junto com publicações e mensagens privadas.
+type Meta struct {
+	// ID identifica a mensagem de ponta a ponta
+	ID string `msgpack:"id,omitempty" json:"id,omitempty"`
+	// Relógio vetorial do canal no envio (WithCausalOrder)
+	VC VectorClock `msgpack:"vc,omitempty" json:"vc,omitempty"`
+	// Relógio lógico híbrido do remetente no envio
+	HLC *HLC `msgpack:"hlc,omitempty" json:"hlc,omitempty"`
+}
+
+// Requisições
+
+type LoginRequest struct {
+	User string `msgpack:"user"`
+	Header
+}
+
+type UsersRequest struct {
+	Header
+}
+
+type ChannelRequest struct {
+	Channel string `msgpack:"channel"`
+	Header
+}
+
+type ChannelsRequest struct {
+	Header
+}
+
+type PublishRequest struct {
+	User    string `msgpack:"user"`
+	Channel string `msgpack:"channel"`
+	Message string `msgpack:"message"`
+	Meta    *Meta  `msgpack:"meta,omitempty"`
+	Header
+}
+
+type MessageRequest struct {
+	Src     string `msgpack:"src"`
+	Dst     string `msgpack:"dst"`
+	Message string `msgpack:"message"`
+	Meta    *Meta  `msgpack:"meta,omitempty"`
+	Header
+}
+
+type HistoryRequest struct {
+	Channel string `msgpack:"channel"`
+	// Timestamp mínimo das publicações (zero para todas)
+	Since int64 `msgpack:"since,omitempty"`
+	// Cursor da página: só publicações anteriores a (Before, BeforeID) na
+	// ordem por timestamp e request_id, a mesma em todas as réplicas
+	Before   int64  `msgpack:"before,omitempty"`
+	BeforeID string `msgpack:"before_id,omitempty"`
+	// Máximo de publicações, as mais recentes (zero usa o padrão do servidor)
+	Limit int `msgpack:"limit,omitempty"`
+	Header
+}
+
+// DMHistoryRequest pede as mensagens privadas trocadas entre User e Peer,
+// nos dois sentidos. Os demais campos são os de HistoryRequest.
+type DMHistoryRequest struct {
+	User     string `msgpack:"user"`
+	Peer     string `msgpack:"peer"`
+	Since    int64  `msgpack:"since,omitempty"`
+	Before   int64  `msgpack:"before,omitempty"`
+	BeforeID string `msgpack:"before_id,omitempty"`
+	Limit    int    `msgpack:"limit,omitempty"`
+	Header
+}
+
+// Respostas
+
+type LoginResponse struct {
+	Status      string `msgpack:"status"`
+	Description string `msgpack:"description,omitempty"`
+	Header
+}
+
+type UsersResponse struct {
+	Users []string `msgpack:"users"`
+	Header
+}
+
+type ChannelResponse struct {
+	Status      string `msgpack:"status"`
+	Description string `msgpack:"description,omitempty"`
+	Header
+}
+
+type ChannelsResponse struct {
+	Channels []string `msgpack:"channels"`
+	Header
+}
+
+type PublishResponse struct {
+	Status  string `msgpack:"status"`
+	Message string `msgpack:"message,omitempty"`
+	Header
+}
+
+type MessageResponse struct {
+	Status  string `msgpack:"status"`
+	Message string `msgpack:"message,omitempty"`
+	Header
+}
+
+type HistoryResponse struct {
+	Status       string        `msgpack:"status"`
+	Description  string        `msgpack:"description,omitempty"`
+	Publications []Publication `msgpack:"publications,omitempty"`
+	// Há publicações mais antigas que as retornadas
+	More bool `msgpack:"more,omitempty"`
+	Header
+}
+
+type DMHistoryResponse struct {
+	Status      string           `msgpack:"status"`
+	Description string           `msgpack:"description,omitempty"`
+	Messages    []PrivateMessage `msgpack:"messages,omitempty"`
+	More        bool             `msgpack:"more,omitempty"`
+	Header
+}
+
+// ErrorResponse é enviada pelo servidor quando a requisição falha
+// internamente, independente do serviço pedido.
+type ErrorResponse struct {
+	Status      string `msgpack:"status"`
+	Description string `msgpack:"description,omitempty"`
+	Header
+}
+
+// Mensagens publicadas pelo servidor no proxy
+
+// Publication é uma mensagem publicada em um canal.
+type Publication struct {
+	User    string `msgpack:"user"`
+	Channel string `msgpack:"channel"`
+	Message string `msgpack:"message"`
+	Meta    *Meta  `msgpack:"meta,omitempty"`
+	// A publicação não chegou pelo proxy: foi recuperada do histórico
+	// depois de uma queda ou de uma conferência periódica. Não faz parte do
+	// protocolo
+	Recovered bool `msgpack:"-"`
+	Header
+}
+
+// PrivateMessage é uma mensagem enviada diretamente a um usuário.
+type PrivateMessage struct {
+	Src     string `msgpack:"src"`
+	Dst     string `msgpack:"dst"`
+	Message string `msgpack:"message"`
+	Meta    *Meta  `msgpack:"meta,omitempty"`
+	Header
+}
+
+// Heartbeat é publicado periodicamente por cada servidor no HeartbeatTopic.
+type Heartbeat struct {
+	Server string `msgpack:"server,omitempty"`
+	Header
+}
+
+func (*LoginRequest) Service() string      { return ServiceLogin }
+func (*UsersRequest) Service() string      { return ServiceUsers }
+func (*ChannelRequest) Service() string    { return ServiceChannel }
+func (*ChannelsRequest) Service() string   { return ServiceChannels }
+func (*PublishRequest) Service() string    { return ServicePublish }
+func (*MessageRequest) Service() string    { return ServiceMessage }
+func (*HistoryRequest) Service() string    { return ServiceHistory }
+func (*DMHistoryRequest) Service() string  { return ServiceDMHistory }
+func (*LoginResponse) Service() string     { return ServiceLogin }
+func (*UsersResponse) Service() string     { return ServiceUsers }
+func (*ChannelResponse) Service() string   { return ServiceChannel }
+func (*ChannelsResponse) Service() string  { return ServiceChannels }
+func (*PublishResponse) Service() string   { return ServicePublish }
+func (*MessageResponse) Service() string   { return ServiceMessage }
+func (*HistoryResponse) Service() string   { return ServiceHistory }
+func (*DMHistoryResponse) Service() string { return ServiceDMHistory }
+func (*ErrorResponse) Service() string     { return ServiceError }
+func (*Publication) Service() string       { return ServicePublication }
+func (*PrivateMessage) Service() string    { return ServicePrivateMessage }
+func (*Heartbeat) Service() string         { return ServiceHeartbeat }
+
+// failure é implementado pelas respostas que carregam um campo status.
+type failure interface {
+	failure() (description string, failed bool)
+}
+
+func (r *LoginResponse) failure() (string, bool)     { return r.Description, r.Status == StatusError }
+func (r *ChannelResponse) failure() (string, bool)   { return r.Description, r.Status == StatusError }
+func (r *PublishResponse) failure() (string, bool)   { return r.Message, r.Status == StatusError }
+func (r *MessageResponse) failure() (string, bool)   { return r.Message, r.Status == StatusError }
+func (r *HistoryResponse) failure() (string, bool)   { return r.Description, r.Status == StatusError }
+func (r *DMHistoryResponse) failure() (string, bool) { return r.Description, r.Status == StatusError }
+func (r *ErrorResponse) failure() (string, bool)     { return r.Description, true }
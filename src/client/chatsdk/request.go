@@ -0,0 +1,203 @@
+package chatsdk
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/pebbe/zmq4"
+)
+
+// Intervalo máximo de cada Poll, para que o cancelamento do contexto seja
+// percebido mesmo com timeouts longos
+const pollSlice = 100 * time.Millisecond
+
+// openReqSocket cria o socket REQ e conecta ao broker. Chamado com reqMu.
+func (c *Client) openReqSocket() error {
+	reqSocket, err := c.zmqContext.NewSocket(zmq4.REQ)
+	if err != nil {
+		return fmt.Errorf("erro ao criar socket REQ: %v", err)
+	}
+
+	err = reqSocket.Connect(c.opts.brokerEndpoint)
+	if err != nil {
+		reqSocket.Close()
+		return fmt.Errorf("erro ao conectar ao broker: %v", err)
+	}
+
+	c.reqSocket = reqSocket
+	return nil
+}
+
+// closeReqSocket descarta o socket REQ sem esperar mensagens pendentes.
+// Chamado com reqMu.
+func (c *Client) closeReqSocket() {
+	if c.reqSocket == nil {
+		return
+	}
+	c.reqSocket.SetLinger(0)
+	c.reqSocket.Close()
+	c.reqSocket = nil
+}
+
+// newRequestID gera um identificador aleatório de 128 bits. Sem ele não se
+// envia nada: um id zerado faria o servidor tratar requisições diferentes
+// como reenvios da mesma.
+func newRequestID() (string, error) {
+	buf := make([]byte, 16)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", fmt.Errorf("erro ao gerar request_id: %v", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// sendRequest envia a requisição e espera a resposta seguindo o padrão Lazy
+// Pirate: se a resposta não chega no prazo da tentativa, o socket REQ (agora
+// em estado inválido) é descartado, recriado e a requisição reenviada, até
+// o limite de tentativas da RetryPolicy ou o fim do contexto. Os reenvios
+// levam o mesmo request_id, para que o servidor não repita o efeito de uma
+// requisição cuja resposta se perdeu.
+func (c *Client) sendRequest(ctx context.Context, request Payload) (Payload, error) {
+	// Incrementar relógio lógico antes de enviar
+	header := request.header()
+	header.Clock = c.clock.Tick()
+	if header.Timestamp == 0 {
+		header.Timestamp = time.Now().UnixMilli()
+	}
+	if header.RequestID == "" {
+		id, err := newRequestID()
+		if err != nil {
+			return nil, err
+		}
+		header.RequestID = id
+	}
+
+	// Serializar mensagem
+	encoded, err := Encode(request)
+	if err != nil {
+		return nil, err
+	}
+
+	c.reqMu.Lock()
+	defer c.reqMu.Unlock()
+
+	policy := c.opts.retry
+	for attempt := 1; ; attempt++ {
+		if c.closed {
+			return nil, ErrClosed
+		}
+		if c.reqSocket == nil {
+			err = c.openReqSocket()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// Enviar requisição
+		sent := time.Now()
+		_, err = c.reqSocket.SendBytes(encoded, 0)
+		if err != nil {
+			c.closeReqSocket()
+			return nil, fmt.Errorf("erro ao enviar mensagem: %v", err)
+		}
+
+		// Receber resposta
+		responseBytes, err := c.waitReply(ctx, policy.Timeout)
+		if err == nil {
+			return c.handleResponse(request, responseBytes, sent, time.Now())
+		}
+
+		// Sem resposta: o REQ ficou esperando um recv que não virá
+		c.closeReqSocket()
+		if err != errAttemptTimeout {
+			return nil, err
+		}
+		if attempt >= policy.Attempts {
+			return nil, fmt.Errorf("%w: '%s' sem resposta após %d tentativas", ErrTimeout, request.Service(), attempt)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(policy.Backoff):
+		}
+	}
+}
+
+// waitReply espera a resposta no socket REQ por até timeout.
+func (c *Client) waitReply(ctx context.Context, timeout time.Duration) ([]byte, error) {
+	poller := zmq4.NewPoller()
+	poller.Add(c.reqSocket, zmq4.POLLIN)
+
+	deadline := time.Now().Add(timeout)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, errAttemptTimeout
+		}
+
+		polled, err := poller.Poll(min(remaining, pollSlice))
+		if err != nil {
+			return nil, fmt.Errorf("erro ao aguardar resposta: %v", err)
+		}
+		if len(polled) > 0 {
+			responseBytes, err := c.reqSocket.RecvBytes(0)
+			if err != nil {
+				return nil, fmt.Errorf("erro ao receber resposta: %v", err)
+			}
+			return responseBytes, nil
+		}
+	}
+}
+
+// handleResponse decodifica a resposta da requisição enviada em sent e
+// recebida em received.
+func (c *Client) handleResponse(request Payload, responseBytes []byte, sent, received time.Time) (Payload, error) {
+	// Deserializar resposta
+	response, err := Decode(responseBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	// Atualizar relógio lógico e a estimativa do relógio do servidor
+	c.clock.Merge(response.header().Clock)
+	c.timeSync.add(sent, received, response.header().Timestamp)
+
+	// Servidores anteriores ao request_id não o repetem na resposta
+	if id := response.header().RequestID; id != "" && id != request.header().RequestID {
+		return nil, fmt.Errorf("resposta de outra requisição para '%s'", request.Service())
+	}
+
+	if f, ok := response.(failure); ok {
+		if description, failed := f.failure(); failed {
+			return nil, &ServerError{Service: request.Service(), Description: description}
+		}
+	}
+	if response.Service() != request.Service() {
+		return nil, fmt.Errorf("resposta inesperada para '%s': '%s'", request.Service(), response.Service())
+	}
+
+	return response, nil
+}
+
+// call envia a requisição e converte a resposta para o tipo esperado.
+func call[T Payload](ctx context.Context, c *Client, request Payload) (T, error) {
+	var zero T
+	response, err := c.sendRequest(ctx, request)
+	if err != nil {
+		return zero, err
+	}
+
+	typed, ok := response.(T)
+	if !ok {
+		return zero, fmt.Errorf("resposta inválida para '%s'", request.Service())
+	}
+	return typed, nil
+}
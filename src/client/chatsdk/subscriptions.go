@@ -0,0 +1,81 @@
+package chatsdk
+
+import (
+	"fmt"
+	"sort"
+)
+
+type subOp struct {
+	topic     string
+	subscribe bool
+}
+
+// Subscribe passa a receber as publicações do tópico (nome do canal ou, para
+// mensagens privadas, do usuário destinatário).
+func (c *Client) Subscribe(topic string) error {
+	if topic == "" {
+		return fmt.Errorf("tópico vazio")
+	}
+
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+	if c.subscriptions[topic] {
+		return nil
+	}
+	c.subscriptions[topic] = true
+	c.pendingSubs = append(c.pendingSubs, subOp{topic: topic, subscribe: true})
+	return nil
+}
+
+// Unsubscribe deixa de receber as publicações do tópico.
+func (c *Client) Unsubscribe(topic string) {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+	if !c.subscriptions[topic] {
+		return
+	}
+	delete(c.subscriptions, topic)
+	c.pendingSubs = append(c.pendingSubs, subOp{topic: topic, subscribe: false})
+}
+
+// Subscriptions retorna os tópicos assinados em ordem alfabética.
+func (c *Client) Subscriptions() []string {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+	topics := make([]string, 0, len(c.subscriptions))
+	for topic := range c.subscriptions {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// isSubscribed compara o tópico exato: o filtro do ZMQ é por prefixo, então
+// "geral" também entrega mensagens de "geral2".
+func (c *Client) isSubscribed(topic string) bool {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+	return c.subscriptions[topic]
+}
+
+// applySubscriptions repassa ao socket SUB as mudanças pendentes. Só deve ser
+// chamada pela goroutine de Listen.
+func (c *Client) applySubscriptions() error {
+	c.subMu.Lock()
+	pending := c.pendingSubs
+	c.pendingSubs = nil
+	c.subMu.Unlock()
+
+	for _, op := range pending {
+		var err error
+		if op.subscribe {
+			err = c.subSocket.SetSubscribe(op.topic)
+		} else {
+			err = c.subSocket.SetUnsubscribe(op.topic)
+		}
+		if err != nil {
+			return fmt.Errorf("erro ao configurar subscription '%s': %v", op.topic, err)
+		}
+	}
+	return nil
+}
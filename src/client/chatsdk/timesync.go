@@ -0,0 +1,101 @@
+package chatsdk
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Respostas recentes consideradas na estimativa da diferença de relógio
+const offsetSamples = 8
+
+// ClockOffset estima a diferença entre o relógio do servidor e o local, a
+// partir do timestamp das respostas.
+type ClockOffset struct {
+	// Horário do servidor menos o local: positivo quando o relógio local
+	// está atrasado
+	Offset time.Duration
+	// Ida e volta da requisição usada na estimativa; o erro da estimativa é
+	// de no máximo RTT/2
+	RTT time.Duration
+	// Respostas consideradas
+	Samples int
+	// Recebimento da resposta usada na estimativa
+	Updated time.Time
+}
+
+// timeSync guarda as últimas medições e usa a de menor RTT, a menos afetada
+// por atrasos de rede (como no NTP).
+type timeSync struct {
+	mu      sync.Mutex
+	samples []ClockOffset
+}
+
+// add registra uma resposta com timestamp serverTime (em milissegundos),
+// para uma requisição enviada em sent e respondida em received.
+func (t *timeSync) add(sent, received time.Time, serverTime int64) {
+	if serverTime <= 0 {
+		return
+	}
+	rtt := received.Sub(sent)
+	// O servidor respondeu, em média, no meio do caminho
+	local := sent.Add(rtt / 2)
+	sample := ClockOffset{
+		Offset:  time.UnixMilli(serverTime).Sub(local),
+		RTT:     rtt,
+		Updated: received,
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.samples = append(t.samples, sample)
+	if len(t.samples) > offsetSamples {
+		t.samples = t.samples[1:]
+	}
+}
+
+// reset descarta as medições anteriores.
+func (t *timeSync) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.samples = nil
+}
+
+func (t *timeSync) estimate() (ClockOffset, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.samples) == 0 {
+		return ClockOffset{}, false
+	}
+
+	best := t.samples[0]
+	for _, s := range t.samples[1:] {
+		if s.RTT < best.RTT {
+			best = s
+		}
+	}
+	best.Samples = len(t.samples)
+	return best, true
+}
+
+// ServerOffset retorna a estimativa atual da diferença de relógio em relação
+// ao servidor, atualizada a cada resposta. ok é false antes da primeira
+// resposta.
+func (c *Client) ServerOffset() (offset ClockOffset, ok bool) {
+	return c.timeSync.estimate()
+}
+
+// MeasureServerOffset descarta as medições anteriores (que podem ser de
+// outra réplica do servidor), faz n requisições leves (channels) e retorna a
+// nova estimativa.
+func (c *Client) MeasureServerOffset(ctx context.Context, n int) (ClockOffset, error) {
+	c.timeSync.reset()
+	for i := 0; i < n; i++ {
+		_, err := c.ListChannels(ctx)
+		if err != nil {
+			return ClockOffset{}, err
+		}
+	}
+	offset, _ := c.ServerOffset()
+	return offset, nil
+}
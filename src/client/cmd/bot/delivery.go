@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Prazo para uma publicação confirmada chegar pelo SUB antes de ser
+	// considerada perdida
+	deliveryTimeout = 10 * time.Second
+	// Espera após assinar um canal antes de publicar nele
+	subscribeSettle = 500 * time.Millisecond
+	// Quantas publicações entregues são lembradas para detectar duplicatas
+	deliveredWindow = 10000
+)
+
+// pendingDelivery é uma publicação enviada que ainda não chegou pelo SUB.
+type pendingDelivery struct {
+	channel string
+	sentAt  time.Time
+	acked   bool
+	failed  bool
+	// Chegou pelo SUB antes da resposta do publish
+	delivered bool
+}
+
+// deliveryTracker confere que cada publicação do bot volta pelo proxy
+// exatamente uma vez e na ordem de envio. As publicações levam em meta.id o
+// prefixo do tracker seguido de um número de sequência.
+type deliveryTracker struct {
+	mu      sync.Mutex
+	prefix  string
+	seq     uint64
+	pending map[uint64]*pendingDelivery
+	lost    map[uint64]bool
+
+	// Últimas sequências entregues, para duplicatas e ordem por canal
+	delivered     map[uint64]bool
+	lastDelivered map[string]uint64
+
+	// O servidor não repassa meta (versão anterior de main.js)
+	unsupported bool
+
+	sent, acked, failed, received      int
+	lostCount, late, duplicates, order int
+	unackedDelivered, recovered        int
+}
+
+func newDeliveryTracker(username string) *deliveryTracker {
+	return &deliveryTracker{
+		// O sufixo aleatório separa execuções diferentes do mesmo bot
+		prefix:        fmt.Sprintf("%s-%04x-", username, rand.Intn(0x10000)),
+		pending:       make(map[uint64]*pendingDelivery),
+		lost:          make(map[uint64]bool),
+		delivered:     make(map[uint64]bool),
+		lastDelivered: make(map[string]uint64),
+	}
+}
+
+// next registra uma publicação prestes a ser enviada e retorna seu id.
+func (t *deliveryTracker) next(channel string) (string, uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.seq++
+	t.sent++
+	t.pending[t.seq] = &pendingDelivery{channel: channel, sentAt: time.Now()}
+	return t.prefix + strconv.FormatUint(t.seq, 10), t.seq
+}
+
+// result registra a resposta do servidor ao publish. Uma publicação com erro
+// continua pendente: o servidor pode tê-la publicado antes de a resposta se
+// perder, e nesse caso a entrega é contada à parte.
+func (t *deliveryTracker) result(seq uint64, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p, ok := t.pending[seq]
+	if !ok {
+		return
+	}
+	if err != nil {
+		p.failed = true
+		t.failed++
+		if p.delivered {
+			delete(t.pending, seq)
+			t.unackedDelivered++
+		}
+		return
+	}
+	p.acked = true
+	t.acked++
+	if p.delivered {
+		delete(t.pending, seq)
+	}
+}
+
+// parse extrai a sequência de um id gerado por este tracker.
+func (t *deliveryTracker) parse(id string) (uint64, bool) {
+	rest, ok := strings.CutPrefix(id, t.prefix)
+	if !ok {
+		return 0, false
+	}
+	seq, err := strconv.ParseUint(rest, 10, 64)
+	return seq, err == nil
+}
+
+// observe trata uma publicação do próprio bot recebida pelo SUB.
+func (t *deliveryTracker) observe(channel, id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if id == "" {
+		if !t.unsupported {
+			t.unsupported = true
+			log.Printf("Servidor não repassa meta das publicações; verificação de entrega desativada")
+		}
+		return
+	}
+	seq, ok := t.parse(id)
+	if !ok {
+		return
+	}
+
+	if t.delivered[seq] {
+		t.duplicates++
+		log.Printf("Publicação %s entregue em duplicidade no canal '%s'", id, channel)
+		return
+	}
+	t.delivered[seq] = true
+	delete(t.delivered, seq-deliveredWindow)
+	t.received++
+
+	if last := t.lastDelivered[channel]; seq < last {
+		t.order++
+		log.Printf("Publicação %s entregue fora de ordem no canal '%s' (após %s%d)", id, channel, t.prefix, last)
+	} else {
+		t.lastDelivered[channel] = seq
+	}
+
+	if t.lost[seq] {
+		delete(t.lost, seq)
+		t.lostCount--
+		t.late++
+		log.Printf("Publicação %s entregue após o prazo no canal '%s'", id, channel)
+		return
+	}
+
+	p, ok := t.pending[seq]
+	if !ok {
+		return
+	}
+	switch {
+	case p.acked:
+		delete(t.pending, seq)
+	case p.failed:
+		delete(t.pending, seq)
+		t.unackedDelivered++
+	default:
+		// A resposta do publish ainda não foi tratada por result
+		p.delivered = true
+	}
+}
+
+// recover conta uma publicação do bot recuperada do histórico pelo SDK. Ela
+// não conta como entrega pelo proxy: se não chegou pelo SUB, continua
+// pendente e é contada como perdida.
+func (t *deliveryTracker) recover(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.parse(id); ok {
+		t.recovered++
+	}
+}
+
+// expire marca como perdidas as publicações confirmadas que não chegaram
+// dentro do prazo. As que falharam no envio são apenas descartadas.
+func (t *deliveryTracker) expire() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.unsupported {
+		return
+	}
+
+	now := time.Now()
+	for seq, p := range t.pending {
+		if now.Sub(p.sentAt) < deliveryTimeout || p.delivered {
+			continue
+		}
+		delete(t.pending, seq)
+		if p.failed || !p.acked {
+			continue
+		}
+		t.lost[seq] = true
+		t.lostCount++
+		log.Printf("Publicação %s%d confirmada pelo servidor mas não entregue no canal '%s'", t.prefix, seq, p.channel)
+	}
+}
+
+// waitChannel espera as publicações pendentes de um canal antes de o bot
+// cancelar a inscrição, para que não sejam contadas como perdidas.
+func (t *deliveryTracker) waitChannel(channel string, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if !t.hasPending(channel) {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.expireChannel(channel)
+}
+
+func (t *deliveryTracker) hasPending(channel string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.unsupported {
+		return false
+	}
+	for _, p := range t.pending {
+		if p.channel == channel && !p.failed && !p.delivered {
+			return true
+		}
+	}
+	return false
+}
+
+// expireChannel força o prazo das publicações pendentes de um canal.
+func (t *deliveryTracker) expireChannel(channel string) {
+	t.mu.Lock()
+	for _, p := range t.pending {
+		if p.channel == channel {
+			p.sentAt = time.Time{}
+		}
+	}
+	t.mu.Unlock()
+	t.expire()
+}
+
+func (t *deliveryTracker) summary() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.unsupported {
+		return "verificação de entrega desativada"
+	}
+	return fmt.Sprintf("enviadas %d, confirmadas %d, com erro %d, entregues %d, pendentes %d, "+
+		"perdidas %d, atrasadas %d, duplicadas %d, fora de ordem %d, entregues sem confirmação %d, "+
+		"recuperadas do histórico %d",
+		t.sent, t.acked, t.failed, t.received, len(t.pending),
+		t.lostCount, t.late, t.duplicates, t.order, t.unackedDelivered, t.recovered)
+}
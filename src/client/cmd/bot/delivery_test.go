@@ -0,0 +1,89 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"chat-client/chatsdk"
+	"chat-client/chatsdk/chattest"
+	"chat-client/internal/config"
+)
+
+// waitFor espera cond ficar verdadeira, falhando depois de 5 segundos.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("tempo esgotado esperando %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestRecoveredPublicationStillLost descarta no proxy uma publicação do bot:
+// a conferência periódica do SDK a recupera do histórico, mas ela não chegou
+// pelo SUB e continua contando como perdida.
+func TestRecoveredPublicationStillLost(t *testing.T) {
+	s, err := chattest.NewServer()
+	if err != nil {
+		t.Fatalf("erro ao criar servidor: %v", err)
+	}
+	defer s.Close()
+	s.AddChannel("geral")
+
+	cfg := &config.Config{
+		Broker:   s.BrokerEndpoint,
+		Proxy:    s.ProxyEndpoint,
+		Username: "bot",
+		Timeout:  500 * time.Millisecond,
+		Retries:  3,
+	}
+	client, err := chatsdk.New(append(cfg.SDKOptions(), chatsdk.WithGapCheck(200*time.Millisecond))...)
+	if err != nil {
+		t.Fatalf("erro ao criar cliente: %v", err)
+	}
+	b := &bot{
+		client:   client,
+		username: cfg.Username,
+		tracker:  newDeliveryTracker(cfg.Username),
+		replies:  make(chan chatsdk.Publication, 16),
+	}
+	defer b.Close()
+	if err := b.Connect(); err != nil {
+		t.Fatalf("erro ao conectar: %v", err)
+	}
+	if err := b.Login(); err != nil {
+		t.Fatalf("erro no login: %v", err)
+	}
+	b.ListenForMessages()
+	b.joinChannel("geral")
+
+	counts := func() (received, recovered int) {
+		b.tracker.mu.Lock()
+		defer b.tracker.mu.Unlock()
+		return b.tracker.received, b.tracker.recovered
+	}
+
+	b.publish("ao vivo")
+	waitFor(t, "entrega ao vivo", func() bool {
+		received, _ := counts()
+		return received == 1
+	})
+
+	s.Mute(true)
+	b.publish("descartada")
+	s.Mute(false)
+	waitFor(t, "recuperação pelo histórico", func() bool {
+		_, recovered := counts()
+		return recovered == 1
+	})
+
+	b.tracker.expireChannel("geral")
+	b.tracker.mu.Lock()
+	defer b.tracker.mu.Unlock()
+	if b.tracker.received != 1 || b.tracker.lostCount != 1 || b.tracker.late != 0 {
+		t.Fatalf("entregues %d, perdidas %d, atrasadas %d; esperadas 1, 1 e 0",
+			b.tracker.received, b.tracker.lostCount, b.tracker.late)
+	}
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"chat-client/chatsdk"
+	"chat-client/internal/config"
+)
+
+const (
+	// Canal em que os usuários virtuais publicam e que todos assinam
+	loadChannel = "carga"
+	// Duração usada quando nem --duration nem --messages são informados
+	defaultLoadDuration = 30 * time.Second
+	// Espera pelas últimas entregas no SUB antes do relatório
+	deliveryGrace = 2 * time.Second
+)
+
+// latencies guarda as amostras de um serviço (ou de um tipo de entrega).
+type latencies struct {
+	samples []time.Duration
+	errors  int
+}
+
+func (l *latencies) percentile(p float64) time.Duration {
+	if len(l.samples) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), l.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	index := int(p/100*float64(len(sorted))+0.5) - 1
+	return sorted[max(0, min(index, len(sorted)-1))]
+}
+
+// loadStats é compartilhado pelos workers e pelas goroutines de Listen.
+type loadStats struct {
+	mu         sync.Mutex
+	requests   map[string]*latencies
+	deliveries map[string]*latencies
+	skipped    int
+}
+
+func newLoadStats() *loadStats {
+	return &loadStats{
+		requests:   make(map[string]*latencies),
+		deliveries: make(map[string]*latencies),
+	}
+}
+
+func entry(table map[string]*latencies, key string) *latencies {
+	l, ok := table[key]
+	if !ok {
+		l = &latencies{}
+		table[key] = l
+	}
+	return l
+}
+
+func (s *loadStats) record(service string, elapsed time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l := entry(s.requests, service)
+	if err != nil {
+		l.errors++
+		return
+	}
+	l.samples = append(l.samples, elapsed)
+}
+
+func (s *loadStats) delivered(service string, timestamp int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l := entry(s.deliveries, service)
+	l.samples = append(l.samples, time.Since(time.UnixMilli(timestamp)))
+}
+
+func (s *loadStats) skip() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.skipped++
+}
+
+// loadTest cria vários usuários virtuais no mesmo processo, cada um com seus
+// próprios sockets REQ e SUB, e gera requisições a uma taxa fixa.
+type loadTest struct {
+	cfg      *config.Config
+	profile  profile
+	users    int
+	rate     float64
+	duration time.Duration
+
+	prefix  string
+	names   []string
+	clients []*chatsdk.Client
+	stats   *loadStats
+}
+
+func runLoadTest(cfg *config.Config, p profile, users int, rate float64) error {
+	if users < 1 {
+		return fmt.Errorf("número de usuários virtuais deve ser pelo menos 1: %d", users)
+	}
+	if rate <= 0 {
+		return fmt.Errorf("taxa deve ser positiva: %v", rate)
+	}
+
+	t := &loadTest{
+		cfg:      cfg,
+		profile:  p,
+		users:    users,
+		rate:     rate,
+		duration: p.Duration,
+		prefix:   fmt.Sprintf("carga%04d_", rand.Intn(10000)),
+		stats:    newLoadStats(),
+	}
+	if t.duration == 0 && p.MaxMessages == 0 {
+		t.duration = defaultLoadDuration
+	}
+	defer t.close()
+
+	err := t.setup()
+	if err != nil {
+		return err
+	}
+
+	elapsed := t.drive()
+	time.Sleep(deliveryGrace)
+	t.report(elapsed)
+	return nil
+}
+
+// setup conecta, faz login e assina o canal de carga com cada usuário virtual.
+func (t *loadTest) setup() error {
+	ctx := context.Background()
+	fmt.Printf("Conectando %d usuários virtuais...\n", t.users)
+
+	for i := 0; i < t.users; i++ {
+		name := fmt.Sprintf("%s%d", t.prefix, i)
+		// Sem recuperação pelo histórico: ela contaria como entrega, com
+		// latência inflada, e somaria pedidos history à carga medida
+		options := append(t.cfg.SDKOptions(), chatsdk.WithBackfillLimit(0), chatsdk.WithGapCheck(0))
+		client, err := chatsdk.New(options...)
+		if err != nil {
+			return err
+		}
+		t.clients = append(t.clients, client)
+		t.names = append(t.names, name)
+
+		err = client.Connect()
+		if err != nil {
+			return err
+		}
+		client.Listen(chatsdk.Handler{
+			Publication: func(p chatsdk.Publication) {
+				if strings.HasPrefix(p.User, t.prefix) && !p.Recovered {
+					t.stats.delivered(p.Service(), p.Timestamp)
+				}
+			},
+			PrivateMessage: func(m chatsdk.PrivateMessage) {
+				if strings.HasPrefix(m.Src, t.prefix) {
+					t.stats.delivered(m.Service(), m.Timestamp)
+				}
+			},
+		})
+
+		start := time.Now()
+		_, err = client.Login(ctx, name)
+		t.stats.record(chatsdk.ServiceLogin, time.Since(start), err)
+		if err != nil {
+			return fmt.Errorf("erro no login de '%s': %w", name, err)
+		}
+
+		err = client.Subscribe(loadChannel)
+		if err != nil {
+			return err
+		}
+	}
+
+	// O canal pode ter sido criado por um teste anterior
+	start := time.Now()
+	_, err := t.clients[0].CreateChannel(ctx, loadChannel)
+	var serverErr *chatsdk.ServerError
+	if errors.As(err, &serverErr) {
+		err = nil
+	}
+	t.stats.record(chatsdk.ServiceChannel, time.Since(start), err)
+	if err != nil {
+		return err
+	}
+
+	// Dar tempo para as assinaturas chegarem ao proxy
+	time.Sleep(500 * time.Millisecond)
+	return nil
+}
+
+// drive dispara requisições na taxa alvo até o fim do teste. Quando todos
+// os usuários estão ocupados, o disparo é contado como perdido em vez de
+// atrasar os seguintes. Retorna a duração efetiva.
+func (t *loadTest) drive() time.Duration {
+	fmt.Printf("Gerando %.1f req/s por %s...\n", t.rate, t.limitDescription())
+
+	jobs := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := range t.clients {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for range jobs {
+				t.request(i)
+			}
+		}(i)
+	}
+
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / t.rate))
+	defer ticker.Stop()
+
+	start := time.Now()
+	var deadline <-chan time.Time
+	if t.duration > 0 {
+		deadline = time.After(t.duration)
+	}
+
+	issued := 0
+loop:
+	for t.profile.MaxMessages == 0 || issued < t.profile.MaxMessages {
+		select {
+		case <-deadline:
+			break loop
+		case <-ticker.C:
+		}
+
+		issued++
+		select {
+		case jobs <- struct{}{}:
+		default:
+			t.stats.skip()
+		}
+	}
+
+	close(jobs)
+	wg.Wait()
+	return time.Since(start)
+}
+
+func (t *loadTest) limitDescription() string {
+	if t.duration > 0 {
+		return t.duration.String()
+	}
+	return fmt.Sprintf("%d requisições", t.profile.MaxMessages)
+}
+
+// request publica no canal de carga ou, na fração DMRatio do perfil, envia
+// uma mensagem privada a outro usuário virtual.
+func (t *loadTest) request(i int) {
+	client := t.clients[i]
+	message := t.profile.message()
+
+	start := time.Now()
+	if t.users > 1 && rand.Float64() < t.profile.DMRatio {
+		dst := t.names[(i+1+rand.Intn(t.users-1))%t.users]
+		_, err := client.SendPrivateMessage(context.Background(), dst, message)
+		t.stats.record(chatsdk.ServiceMessage, time.Since(start), err)
+		return
+	}
+
+	_, err := client.PublishMessage(context.Background(), loadChannel, message)
+	t.stats.record(chatsdk.ServicePublish, time.Since(start), err)
+}
+
+func (t *loadTest) close() {
+	for _, client := range t.clients {
+		client.Close()
+	}
+}
+
+func formatLatency(d time.Duration) string {
+	return fmt.Sprintf("%.1fms", float64(d)/float64(time.Millisecond))
+}
+
+func (t *loadTest) report(elapsed time.Duration) {
+	t.stats.mu.Lock()
+	defer t.stats.mu.Unlock()
+
+	total := 0
+	for _, service := range []string{chatsdk.ServicePublish, chatsdk.ServiceMessage} {
+		if l, ok := t.stats.requests[service]; ok {
+			total += len(l.samples) + l.errors
+		}
+	}
+
+	fmt.Println()
+	fmt.Println("=== Resultado do teste de carga ===")
+	fmt.Printf("Usuários virtuais: %d\n", t.users)
+	fmt.Printf("Duração: %v\n", elapsed.Round(time.Millisecond))
+	fmt.Printf("Taxa alvo: %.1f req/s, obtida: %.1f req/s\n", t.rate, float64(total)/elapsed.Seconds())
+	fmt.Printf("Disparos perdidos (todos os usuários ocupados): %d\n", t.stats.skipped)
+
+	fmt.Println()
+	fmt.Println("Latência das requisições:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "serviço\ttotal\terros\ttaxa de erro\tp50\tp95\tp99\t")
+	for _, service := range []string{chatsdk.ServiceLogin, chatsdk.ServiceChannel, chatsdk.ServicePublish, chatsdk.ServiceMessage} {
+		l, ok := t.stats.requests[service]
+		if !ok {
+			continue
+		}
+		count := len(l.samples) + l.errors
+		fmt.Fprintf(w, "%s\t%d\t%d\t%.2f%%\t%s\t%s\t%s\t\n", service, count, l.errors,
+			100*float64(l.errors)/float64(count),
+			formatLatency(l.percentile(50)), formatLatency(l.percentile(95)), formatLatency(l.percentile(99)))
+	}
+	w.Flush()
+
+	// Cada publicação aceita deve chegar a todos os usuários virtuais e cada
+	// mensagem privada ao destinatário
+	expected := map[string]int{}
+	if l, ok := t.stats.requests[chatsdk.ServicePublish]; ok {
+		expected[chatsdk.ServicePublication] = len(l.samples) * t.users
+	}
+	if l, ok := t.stats.requests[chatsdk.ServiceMessage]; ok {
+		expected[chatsdk.ServicePrivateMessage] = len(l.samples)
+	}
+
+	fmt.Println()
+	fmt.Println("Latência de entrega (envio → SUB):")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "tipo\tesperadas\trecebidas\tp50\tp95\tp99\t")
+	for _, service := range []string{chatsdk.ServicePublication, chatsdk.ServicePrivateMessage} {
+		want, ok := expected[service]
+		if !ok {
+			continue
+		}
+		l := entry(t.stats.deliveries, service)
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t%s\t\n", service, want, len(l.samples),
+			formatLatency(l.percentile(50)), formatLatency(l.percentile(95)), formatLatency(l.percentile(99)))
+	}
+	w.Flush()
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"chat-client/chatsdk"
+	"chat-client/internal/config"
+)
+
+type bot struct {
+	client       *chatsdk.Client
+	username     string
+	profile      profile
+	messageCount int
+	attempts     int
+	channels     []string
+	users        []string
+	current      string
+
+	// Confere a entrega das próprias publicações pelo proxy
+	tracker *deliveryTracker
+	replyID int
+
+	// Menções a responder, tratadas fora da goroutine de Listen
+	replies chan chatsdk.Publication
+}
+
+func randomUsername() string {
+	usernames := []string{
+		"BotAlpha", "BotBeta", "BotGamma", "BotDelta", "BotEpsilon",
+		"BotZeta", "BotEta", "BotTheta", "BotIota", "BotKappa",
+		"BotLambda", "BotMu", "BotNu", "BotXi", "BotOmicron",
+		"BotPi", "BotRho", "BotSigma", "BotTau", "BotUpsilon",
+	}
+
+	return usernames[rand.Intn(len(usernames))] + fmt.Sprintf("%d", rand.Intn(1000))
+}
+
+func newBot(cfg *config.Config, p profile) *bot {
+	// Gerar nome aleatório se não configurado
+	username := cfg.Username
+	if username == "" {
+		username = randomUsername()
+	}
+
+	options := append(cfg.SDKOptions(), chatsdk.WithUsername(username))
+	client, err := chatsdk.New(options...)
+	if err != nil {
+		log.Fatal("Erro ao criar cliente:", err)
+	}
+
+	return &bot{
+		client:       client,
+		username:     username,
+		profile:      p,
+		messageCount: 0,
+		channels:     []string{},
+		tracker:      newDeliveryTracker(username),
+		replies:      make(chan chatsdk.Publication, 16),
+	}
+}
+
+func (b *bot) Connect() error {
+	err := b.client.Connect()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Bot '%s' conectado ao sistema\n", b.username)
+	return nil
+}
+
+func (b *bot) Close() {
+	b.client.Close()
+}
+
+func (b *bot) Login() error {
+	_, err := b.client.Login(context.Background(), b.username)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Bot '%s' logado com sucesso\n", b.username)
+	return nil
+}
+
+func (b *bot) CreateChannel(channelName string) error {
+	_, err := b.client.CreateChannel(context.Background(), channelName)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Bot '%s' criou canal '%s'\n", b.username, channelName)
+	return nil
+}
+
+// joinChannel troca a inscrição do bot para o canal em que ele vai publicar.
+func (b *bot) joinChannel(channel string) {
+	if b.current == channel {
+		return
+	}
+	if b.current != "" {
+		b.tracker.waitChannel(b.current, deliveryGrace)
+		b.client.Unsubscribe(b.current)
+	}
+	err := b.client.Subscribe(channel)
+	if err != nil {
+		log.Printf("Erro ao assinar canal: %v", err)
+	}
+	b.current = channel
+
+	// A assinatura leva algum tempo para chegar ao proxy; publicações antes
+	// disso não voltariam para o bot e seriam contadas como perdidas
+	time.Sleep(subscribeSettle)
+}
+
+func (b *bot) ListenForMessages() {
+	b.client.Listen(chatsdk.Handler{
+		Publication: func(p chatsdk.Publication) {
+			fmt.Printf("[%s] Bot '%s' recebeu: %s: %s\n", p.Channel, b.username, p.User, p.Message)
+			switch {
+			case p.User != b.username:
+			case p.Recovered:
+				// Veio do histórico, não do proxy: não prova a entrega
+				b.tracker.recover(metaID(p.Meta))
+			default:
+				b.tracker.observe(p.Channel, metaID(p.Meta))
+			}
+			if b.profile.ReplyToMentions && p.User != b.username && b.mentioned(p.Message) {
+				select {
+				case b.replies <- p:
+				default:
+					// Fila cheia: a menção fica sem resposta
+				}
+			}
+		},
+		PrivateMessage: func(m chatsdk.PrivateMessage) {
+			fmt.Printf("[PRIVADO] Bot '%s' recebeu de %s: %s\n", b.username, m.Src, m.Message)
+		},
+		State: func(e chatsdk.ConnectionEvent) {
+			switch {
+			case e.State == chatsdk.StateDisconnected:
+				log.Printf("Bot '%s' perdeu a conexão com o proxy: %v", b.username, e.Err)
+			case e.State == chatsdk.StateConnected && !e.Since.IsZero():
+				log.Printf("Bot '%s' reconectado ao proxy após %v sem mensagens",
+					b.username, time.Since(e.Since).Round(time.Second))
+			}
+		},
+	})
+}
+
+func (b *bot) mentioned(message string) bool {
+	for _, word := range strings.Fields(message) {
+		if strings.TrimRight(word, ",.:;!?") == "@"+b.username {
+			return true
+		}
+	}
+	return false
+}
+
+// replyToMentions responde cada menção no mesmo canal. A resposta cita o nome
+// sem "@" para que dois bots não fiquem respondendo um ao outro.
+func (b *bot) replyToMentions(stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case p := <-b.replies:
+			time.Sleep(b.profile.interval())
+			message := fmt.Sprintf("%s, %s", p.User, b.profile.message())
+			// Respostas não são verificadas, mas levam um id para não serem
+			// confundidas com um servidor que descarta meta
+			b.replyID++
+			meta := &chatsdk.Meta{ID: fmt.Sprintf("%s-resposta-%d", b.username, b.replyID)}
+			_, err := b.client.PublishMessageWithMeta(context.Background(), p.Channel, message, meta)
+			if err != nil {
+				log.Printf("Erro ao responder menção: %v", err)
+				continue
+			}
+			fmt.Printf("Bot '%s' respondeu %s no canal '%s': %s\n", b.username, p.User, p.Channel, message)
+		}
+	}
+}
+
+// refreshChannels atualiza a lista de canais e cria um se não houver nenhum.
+func (b *bot) refreshChannels() {
+	response, err := b.client.ListChannels(context.Background())
+	if err != nil {
+		log.Printf("Erro ao listar canais: %v", err)
+	} else {
+		b.channels = response.Channels
+	}
+
+	// Se não há canais, criar um
+	if len(b.channels) == 0 {
+		channelName := fmt.Sprintf("canal%d", rand.Intn(1000))
+		err := b.CreateChannel(channelName)
+		if err != nil {
+			log.Printf("Erro ao criar canal: %v", err)
+		} else {
+			b.channels = append(b.channels, channelName)
+		}
+	}
+}
+
+// refreshUsers atualiza os destinatários possíveis de mensagens privadas.
+func (b *bot) refreshUsers() {
+	response, err := b.client.ListUsers(context.Background())
+	if err != nil {
+		log.Printf("Erro ao listar usuários: %v", err)
+		return
+	}
+
+	b.users = b.users[:0]
+	for _, user := range response.Users {
+		if user != b.username {
+			b.users = append(b.users, user)
+		}
+	}
+}
+
+func metaID(meta *chatsdk.Meta) string {
+	if meta == nil {
+		return ""
+	}
+	return meta.ID
+}
+
+func (b *bot) publish(message string) {
+	channel := b.current
+	id, seq := b.tracker.next(channel)
+	_, err := b.client.PublishMessageWithMeta(context.Background(), channel, message, &chatsdk.Meta{ID: id})
+	b.tracker.result(seq, err)
+	if err != nil {
+		log.Printf("Erro ao publicar mensagem: %v", err)
+
+		// Se canal não existe, escolher outro ou criar um novo
+		response, listErr := b.client.ListChannels(context.Background())
+		if listErr == nil && len(response.Channels) > 0 {
+			b.channels = response.Channels
+			b.joinChannel(b.channels[rand.Intn(len(b.channels))])
+		} else if len(b.channels) == 0 {
+			// Criar um novo canal se não há nenhum
+			channelName := fmt.Sprintf("canal%d", rand.Intn(10000))
+			err := b.CreateChannel(channelName)
+			if err == nil {
+				b.channels = []string{channelName}
+				b.joinChannel(channelName)
+			}
+		}
+		// Continuar mesmo se falhar
+		return
+	}
+
+	b.messageCount++
+	fmt.Printf("Bot '%s' enviou mensagem %d no canal '%s': %s\n",
+		b.username, b.messageCount, channel, message)
+}
+
+func (b *bot) sendPrivate(message string) {
+	user := b.users[rand.Intn(len(b.users))]
+	_, err := b.client.SendPrivateMessage(context.Background(), user, message)
+	if err != nil {
+		log.Printf("Erro ao enviar mensagem privada: %v", err)
+		return
+	}
+
+	b.messageCount++
+	fmt.Printf("Bot '%s' enviou mensagem %d em privado para '%s': %s\n",
+		b.username, b.messageCount, user, message)
+}
+
+// finished indica se a execução atingiu o limite de mensagens ou de tempo.
+func (b *bot) finished(deadline time.Time) bool {
+	if b.profile.MaxMessages > 0 && b.attempts >= b.profile.MaxMessages {
+		return true
+	}
+	return !deadline.IsZero() && !time.Now().Before(deadline)
+}
+
+func (b *bot) Run() {
+	// Fazer login
+	err := b.Login()
+	if err != nil {
+		log.Fatal("Erro no login:", err)
+	}
+
+	// Iniciar escuta de mensagens
+	b.ListenForMessages()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	if b.profile.ReplyToMentions {
+		go b.replyToMentions(stop)
+	}
+
+	var deadline time.Time
+	if b.profile.Duration > 0 {
+		deadline = time.Now().Add(b.profile.Duration)
+	}
+
+	// Loop principal
+	for !b.finished(deadline) {
+		// Atualizar listas de canais e usuários
+		b.refreshChannels()
+		if b.profile.DMRatio > 0 {
+			b.refreshUsers()
+		}
+
+		// Trocar de canal (ou escolher o primeiro)
+		if len(b.channels) > 0 && (b.current == "" || rand.Float64() < b.profile.Hop) {
+			b.joinChannel(b.channels[rand.Intn(len(b.channels))])
+		}
+
+		// Enviar uma rajada de mensagens
+		for i := 0; i < b.profile.Burst && !b.finished(deadline); i++ {
+			message := b.profile.message()
+			b.attempts++
+			if len(b.users) > 0 && rand.Float64() < b.profile.DMRatio {
+				b.sendPrivate(message)
+			} else if b.current != "" {
+				b.publish(message)
+			}
+
+			// Pausa entre mensagens
+			time.Sleep(b.profile.interval())
+		}
+
+		b.tracker.expire()
+		if b.finished(deadline) {
+			break
+		}
+
+		// Pausa antes do próximo ciclo
+		fmt.Printf("Bot '%s' entrega: %s\n", b.username, b.tracker.summary())
+		fmt.Printf("Bot '%s' pausando por %v...\n", b.username, b.profile.Pause)
+		time.Sleep(b.profile.Pause)
+	}
+
+	if b.current != "" {
+		b.tracker.waitChannel(b.current, deliveryTimeout)
+	}
+	fmt.Printf("Bot '%s' entrega: %s\n", b.username, b.tracker.summary())
+	fmt.Printf("Bot '%s' terminou após enviar %d mensagens\n", b.username, b.messageCount)
+}
+
+func main() {
+	rand.Seed(time.Now().UnixNano())
+
+	profileName := flag.String("profile", "", "perfil de comportamento: "+profileNames()+" (env "+config.EnvBotProfile+")")
+	corpus := flag.String("corpus", "", "arquivo com uma mensagem por linha")
+	messages := flag.Int("messages", 0, "encerrar após enviar este número de mensagens")
+	duration := flag.Duration("duration", 0, "encerrar após este tempo de execução")
+	loadtest := flag.Bool("loadtest", false, "modo teste de carga com vários usuários virtuais")
+	users := flag.Int("users", 10, "usuários virtuais no teste de carga")
+	rate := flag.Float64("rate", 10, "requisições por segundo no teste de carga")
+
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Fatal("Erro na configuração:", err)
+	}
+
+	// Flags do bot têm prioridade sobre a seção bot da configuração
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "profile":
+			cfg.Bot.Profile = *profileName
+		case "corpus":
+			cfg.Bot.Corpus = *corpus
+		case "messages":
+			cfg.Bot.Messages = *messages
+		case "duration":
+			cfg.Bot.Duration = *duration
+		}
+	})
+
+	p, err := resolveProfile(cfg.Bot)
+	if err != nil {
+		log.Fatal("Erro no perfil do bot:", err)
+	}
+
+	if *loadtest {
+		err = runLoadTest(cfg, p, *users, *rate)
+		if err != nil {
+			log.Fatal("Erro no teste de carga:", err)
+		}
+		return
+	}
+
+	b := newBot(cfg, p)
+	defer b.Close()
+
+	err = b.Connect()
+	if err != nil {
+		log.Fatal("Erro ao conectar:", err)
+	}
+
+	b.Run()
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"chat-client/internal/config"
+)
+
+// Perfil usado quando nenhum é configurado: o comportamento original do bot
+const defaultProfile = "padrao"
+
+// profile descreve o comportamento do bot.
+type profile struct {
+	// Pausa entre mensagens, sorteada entre os dois valores
+	MinInterval time.Duration
+	MaxInterval time.Duration
+	// Mensagens por rajada e pausa entre rajadas
+	Burst int
+	Pause time.Duration
+	// Probabilidade de trocar de canal no início de cada rajada
+	Hop float64
+	// Fração das mensagens enviadas em privado a um usuário sorteado
+	DMRatio float64
+	// Responder quando alguém menciona @bot em um canal assinado
+	ReplyToMentions bool
+
+	Messages []string
+	// Limites da execução; zero executa para sempre
+	MaxMessages int
+	Duration    time.Duration
+}
+
+// Mensagens pré-definidas
+var defaultMessages = []string{
+	"Olá pessoal!",
+	"Como vocês estão?",
+	"Alguém quer conversar?",
+	"Que dia bonito hoje!",
+	"Estou testando o sistema",
+	"Funcionando perfeitamente!",
+	"ZeroMQ é incrível!",
+	"Sistemas distribuídos são fascinantes",
+	"Vamos fazer mais testes?",
+	"Até a próxima mensagem!",
+}
+
+// Perfis embutidos, selecionados por --profile ou bot.profile
+var profiles = map[string]profile{
+	// 10 mensagens a cada 1–3s no mesmo canal, 10s de pausa
+	"padrao": {
+		MinInterval: 1 * time.Second,
+		MaxInterval: 3 * time.Second,
+		Burst:       10,
+		Pause:       10 * time.Second,
+		Hop:         1,
+	},
+	// Poucas mensagens espaçadas, conversa em privado e responde menções
+	"calmo": {
+		MinInterval:     5 * time.Second,
+		MaxInterval:     15 * time.Second,
+		Burst:           3,
+		Pause:           30 * time.Second,
+		Hop:             0.2,
+		DMRatio:         0.1,
+		ReplyToMentions: true,
+	},
+	// Conversa contínua, trocando de canal com frequência
+	"tagarela": {
+		MinInterval:     200 * time.Millisecond,
+		MaxInterval:     1 * time.Second,
+		Burst:           20,
+		Pause:           5 * time.Second,
+		Hop:             0.3,
+		DMRatio:         0.2,
+		ReplyToMentions: true,
+	},
+	// Rajadas curtas e intensas separadas por longas pausas
+	"rajada": {
+		MinInterval: 0,
+		MaxInterval: 100 * time.Millisecond,
+		Burst:       50,
+		Pause:       30 * time.Second,
+		Hop:         0.5,
+		DMRatio:     0.05,
+	},
+}
+
+func profileNames() string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// resolveProfile parte do perfil escolhido e aplica os ajustes da configuração.
+func resolveProfile(c config.BotConfig) (profile, error) {
+	name := c.Profile
+	if name == "" {
+		name = defaultProfile
+	}
+	p, ok := profiles[name]
+	if !ok {
+		return profile{}, fmt.Errorf("perfil de bot desconhecido '%s' (disponíveis: %s)", name, profileNames())
+	}
+
+	if c.MinInterval != nil {
+		p.MinInterval = *c.MinInterval
+	}
+	if c.MaxInterval != nil {
+		p.MaxInterval = *c.MaxInterval
+	}
+	if c.Burst != nil {
+		p.Burst = *c.Burst
+	}
+	if c.Pause != nil {
+		p.Pause = *c.Pause
+	}
+	if c.Hop != nil {
+		p.Hop = *c.Hop
+	}
+	if c.DMRatio != nil {
+		p.DMRatio = *c.DMRatio
+	}
+	if c.ReplyToMentions != nil {
+		p.ReplyToMentions = *c.ReplyToMentions
+	}
+	p.MaxMessages = c.Messages
+	p.Duration = c.Duration
+
+	p.Messages = defaultMessages
+	if c.Corpus != "" {
+		messages, err := loadCorpus(c.Corpus)
+		if err != nil {
+			return profile{}, err
+		}
+		p.Messages = messages
+	}
+
+	return p, p.validate()
+}
+
+func (p profile) validate() error {
+	if p.MinInterval < 0 || p.MaxInterval < p.MinInterval {
+		return fmt.Errorf("intervalo entre mensagens inválido: %v a %v", p.MinInterval, p.MaxInterval)
+	}
+	if p.Burst < 1 {
+		return fmt.Errorf("burst deve ser pelo menos 1: %d", p.Burst)
+	}
+	if p.Pause < 0 {
+		return fmt.Errorf("pausa entre rajadas inválida: %v", p.Pause)
+	}
+	if p.Hop < 0 || p.Hop > 1 {
+		return fmt.Errorf("hop deve estar entre 0 e 1: %v", p.Hop)
+	}
+	if p.DMRatio < 0 || p.DMRatio > 1 {
+		return fmt.Errorf("dm_ratio deve estar entre 0 e 1: %v", p.DMRatio)
+	}
+	if p.MaxMessages < 0 || p.Duration < 0 {
+		return fmt.Errorf("limites de execução não podem ser negativos")
+	}
+	return nil
+}
+
+// loadCorpus lê uma mensagem por linha, ignorando linhas vazias e as
+// iniciadas por #.
+func loadCorpus(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir corpus: %v", err)
+	}
+	defer file.Close()
+
+	var messages []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		messages = append(messages, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("erro ao ler corpus %s: %v", path, err)
+	}
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("corpus %s não tem mensagens", path)
+	}
+	return messages, nil
+}
+
+// interval sorteia a pausa até a próxima mensagem.
+func (p profile) interval() time.Duration {
+	if p.MaxInterval <= p.MinInterval {
+		return p.MinInterval
+	}
+	return p.MinInterval + time.Duration(rand.Int63n(int64(p.MaxInterval-p.MinInterval)))
+}
+
+func (p profile) message() string {
+	return p.Messages[rand.Intn(len(p.Messages))]
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"chat-client/chatsdk"
+	"chat-client/internal/config"
+)
+
+// Códigos de saída dos subcomandos e do modo --script
+const (
+	exitOK = 0
+	// O servidor recusou o comando (canal inexistente, por exemplo)
+	exitFailure = 1
+	// Argumentos, configuração ou script inválidos
+	exitUsage = 2
+	// Nenhuma réplica do servidor respondeu
+	exitUnavailable = 3
+)
+
+// exitCode escolhe o código de saída para o erro de um comando.
+func exitCode(err error) int {
+	var usage usageError
+	switch {
+	case err == nil, err == errQuit:
+		return exitOK
+	case errors.As(err, &usage), errors.Is(err, errUnknownCommand):
+		return exitUsage
+	case errors.Is(err, chatsdk.ErrTimeout):
+		return exitUnavailable
+	}
+	return exitFailure
+}
+
+// subcommand é um comando executado direto da linha de comando, sem o REPL:
+// client <nome> [flags].
+type subcommand struct {
+	usage       string
+	description string
+	// Registra as flags próprias em fs e retorna a função que executa o
+	// comando depois do login
+	setup func(fs *flag.FlagSet) func(sh *shell) error
+}
+
+var subcommands = map[string]subcommand{
+	"users": {
+		usage:       "users",
+		description: "Listar usuários, um por linha",
+		setup: func(*flag.FlagSet) func(*shell) error {
+			return func(sh *shell) error {
+				response, err := sh.client.ListUsers(sh.ctx)
+				if err != nil {
+					return err
+				}
+				sh.printList(chatsdk.ServiceUsers, response.Header, "users", response.Users)
+				return nil
+			}
+		},
+	},
+	"channels": {
+		usage:       "channels",
+		description: "Listar canais, um por linha",
+		setup: func(*flag.FlagSet) func(*shell) error {
+			return func(sh *shell) error {
+				response, err := sh.client.ListChannels(sh.ctx)
+				if err != nil {
+					return err
+				}
+				sh.printList(chatsdk.ServiceChannels, response.Header, "channels", response.Channels)
+				return nil
+			}
+		},
+	},
+	"create": {
+		usage:       "create --channel <canal>",
+		description: "Criar canal",
+		setup: func(fs *flag.FlagSet) func(*shell) error {
+			channel := fs.String("channel", "", "canal a criar")
+			return func(sh *shell) error {
+				if *channel == "" {
+					return usageError("client create --channel <canal>")
+				}
+				response, err := sh.client.CreateChannel(sh.ctx, *channel)
+				if err != nil {
+					return err
+				}
+				sh.emit(chatsdk.ServiceChannel, response.Header, outputFields{"channel": *channel}, func() {
+					fmt.Fprintf(sh.out, "Canal '%s' criado com sucesso\n", *channel)
+				})
+				return nil
+			}
+		},
+	},
+	"publish": {
+		usage:       "publish --channel <canal> --message <texto|->",
+		description: "Publicar no canal (- lê a mensagem da entrada padrão)",
+		setup: func(fs *flag.FlagSet) func(*shell) error {
+			channel := fs.String("channel", "", "canal de destino")
+			message := fs.String("message", "", "mensagem; - lê da entrada padrão")
+			return func(sh *shell) error {
+				if *channel == "" || *message == "" || sh.client.Username() == "" {
+					return usageError("client publish --channel <canal> --message <texto|-> --user <nome>")
+				}
+				text, err := messageArg(*message)
+				if err != nil {
+					return err
+				}
+				response, err := sh.client.PublishMessage(sh.ctx, *channel, text)
+				if err != nil {
+					return err
+				}
+				sh.emit(chatsdk.ServicePublish, response.Header, outputFields{"channel": *channel, "message": text}, func() {
+					fmt.Fprintf(sh.out, "Mensagem publicada no canal '%s'\n", *channel)
+				})
+				return nil
+			}
+		},
+	},
+	"msg": {
+		usage:       "msg --to <usuário> --message <texto|->",
+		description: "Enviar mensagem privada (- lê a mensagem da entrada padrão)",
+		setup: func(fs *flag.FlagSet) func(*shell) error {
+			to := fs.String("to", "", "destinatário")
+			message := fs.String("message", "", "mensagem; - lê da entrada padrão")
+			return func(sh *shell) error {
+				if *to == "" || *message == "" || sh.client.Username() == "" {
+					return usageError("client msg --to <usuário> --message <texto|-> --user <nome>")
+				}
+				text, err := messageArg(*message)
+				if err != nil {
+					return err
+				}
+				response, err := sh.client.SendPrivateMessage(sh.ctx, *to, text)
+				if err != nil {
+					return err
+				}
+				sh.emit(chatsdk.ServiceMessage, response.Header, outputFields{"dst": *to, "message": text}, func() {
+					fmt.Fprintf(sh.out, "Mensagem enviada para '%s'\n", *to)
+				})
+				return nil
+			}
+		},
+	},
+	"history": {
+		usage:       "history --channel <canal> [--limit n] [n] [before:<horário> [id:<request_id>]]",
+		description: "Mostrar mensagens anteriores do canal (argumentos como no REPL, depois das flags)",
+		setup: func(fs *flag.FlagSet) func(*shell) error {
+			channel := fs.String("channel", "", "canal")
+			limit := fs.Int("limit", defaultHistorySize, "quantidade de mensagens")
+			return func(sh *shell) error {
+				if *channel == "" {
+					return usageError("client history --channel <canal> [--limit n] [n] [before:<horário> [id:<request_id>]]")
+				}
+				// --limit vem primeiro para que um n posicional prevaleça
+				args := append([]string{strconv.Itoa(*limit)}, fs.Args()...)
+				return sh.channelHistory("client history --channel", *channel, args)
+			}
+		},
+	},
+}
+
+// printList escreve uma lista, um item por linha na saída de texto.
+func (s *shell) printList(service string, header chatsdk.Header, name string, items []string) {
+	s.emit(service, header, outputFields{name: items}, func() {
+		for _, item := range items {
+			fmt.Fprintln(s.out, item)
+		}
+	})
+}
+
+// messageArg retorna a mensagem informada ou, para "-", a entrada padrão
+// sem a quebra de linha final.
+func messageArg(value string) (string, error) {
+	if value != "-" {
+		return value, nil
+	}
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", fmt.Errorf("erro ao ler mensagem da entrada padrão: %v", err)
+	}
+	text := strings.TrimRight(string(data), "\r\n")
+	if text == "" {
+		return "", usageError("mensagem vazia na entrada padrão")
+	}
+	return text, nil
+}
+
+// printSubcommands lista os subcomandos na ajuda do binário.
+func printSubcommands(w io.Writer) {
+	names := make([]string, 0, len(subcommands))
+	for name := range subcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "Subcomandos (aceitam também as flags de configuração, como --user e --output, e --json):")
+	for _, name := range names {
+		cmd := subcommands[name]
+		fmt.Fprintf(w, "  client %s - %s\n", cmd.usage, cmd.description)
+	}
+}
+
+// runSubcommand executa um subcomando e retorna o código de saída.
+func runSubcommand(name string, args []string) int {
+	cmd, ok := subcommands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Subcomando desconhecido: %s\n", name)
+		printSubcommands(os.Stderr)
+		return exitUsage
+	}
+
+	fs := flag.NewFlagSet("client "+name, flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "o mesmo que --output json")
+	run := cmd.setup(fs)
+	cfg, err := config.Load(fs, args)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Erro na configuração:", err)
+		return exitUsage
+	}
+	if *asJSON {
+		cfg.Output = config.OutputJSON
+	}
+
+	return withBatchShell(cfg, run)
+}
+
+// runScript executa os comandos do REPL lidos de path ("-" para a entrada
+// padrão), um por linha, parando no primeiro que falhar. Linhas vazias e
+// iniciadas por # são ignoradas.
+func runScript(cfg *config.Config, path string) int {
+	input := os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Erro ao abrir script: %v\n", err)
+			return exitUsage
+		}
+		defer file.Close()
+		input = file
+	}
+
+	return withBatchShell(cfg, func(sh *shell) error {
+		scanner := bufio.NewScanner(input)
+		for number := 1; scanner.Scan(); number++ {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+
+			err := sh.execute(line)
+			if err == errQuit {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("%s:%d: %w", path, number, err)
+			}
+		}
+		err := scanner.Err()
+		if err != nil {
+			return fmt.Errorf("erro ao ler script: %v", err)
+		}
+		return nil
+	})
+}
+
+// withBatchShell conecta, faz login com o usuário da configuração (se houver)
+// e executa run em um shell sem fila de saída nem armazenamento, já que o
+// processo termina em seguida. Erros vão para a saída de erro.
+func withBatchShell(cfg *config.Config, run func(sh *shell) error) int {
+	client, err := chatsdk.New(cfg.SDKOptions()...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Erro ao criar cliente:", err)
+		return exitFailure
+	}
+	defer client.Close()
+
+	err = client.Connect()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Erro ao conectar:", err)
+		return exitUnavailable
+	}
+
+	sh := &shell{
+		ctx:     context.Background(),
+		client:  client,
+		out:     os.Stdout,
+		batch:   true,
+		display: newDisplayQueue(config.OrderArrival),
+		output:  cfg.Output,
+	}
+
+	if cfg.Username != "" {
+		_, err = client.Login(sh.ctx, cfg.Username)
+	}
+	if err == nil {
+		err = run(sh)
+	}
+	// Na saída de texto, erros vão para a saída de erro; em JSON, seguem no
+	// mesmo fluxo dos resultados
+	if err != nil && sh.structured() {
+		sh.report(err)
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, describeError(err))
+	}
+	return exitCode(err)
+}
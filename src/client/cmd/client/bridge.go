@@ -0,0 +1,366 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"chat-client/chatsdk"
+	"chat-client/internal/config"
+)
+
+// Tamanho máximo de uma linha da entrada no modo --bridge
+const bridgeMaxLine = 1 << 20
+
+// bridgeRequest é uma linha da entrada no modo --bridge. Os campos usados
+// dependem de op.
+type bridgeRequest struct {
+	// Identificador escolhido pelo programa, repetido na resposta (qualquer
+	// valor JSON)
+	ID json.RawMessage `json:"id,omitempty"`
+	Op string          `json:"op"`
+
+	User    string        `json:"user"`
+	Channel string        `json:"channel"`
+	To      string        `json:"to"`
+	Peer    string        `json:"peer"`
+	Message string        `json:"message"`
+	Meta    *chatsdk.Meta `json:"meta"`
+
+	// Paginação de history e dm_history
+	Limit    int    `json:"limit"`
+	Since    int64  `json:"since"`
+	Before   int64  `json:"before"`
+	BeforeID string `json:"before_id"`
+}
+
+// bridge é o modo --bridge: lê requisições JSON, uma por linha, na entrada
+// padrão e escreve na saída padrão as respostas e os eventos recebidos, um
+// objeto JSON por linha. As requisições são atendidas em ordem.
+type bridge struct {
+	ctx    context.Context
+	client *chatsdk.Client
+
+	// Respostas e eventos são escritos por goroutines diferentes
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+// write escreve um objeto na saída.
+func (b *bridge) write(object outputFields) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.encoder.Encode(object)
+}
+
+// event escreve um evento (type "event"), com o timestamp e o clock de header.
+func (b *bridge) event(service string, header chatsdk.Header, fields outputFields) {
+	object := outputFields{
+		"type":      "event",
+		"service":   service,
+		"timestamp": header.Timestamp,
+		"clock":     header.Clock,
+	}
+	for name, value := range fields {
+		object[name] = value
+	}
+	b.write(object)
+}
+
+// respond escreve a resposta (type "response") da requisição id: com ok e os
+// campos do resultado ou, se err não for nil, com error e code.
+func (b *bridge) respond(id json.RawMessage, service string, header chatsdk.Header, fields outputFields, err error) {
+	object := outputFields{
+		"type":    "response",
+		"service": service,
+		"ok":      err == nil,
+	}
+	if id != nil {
+		object["id"] = id
+	}
+	if err != nil {
+		object["error"] = err.Error()
+		object["code"] = errorCode(err)
+		b.write(object)
+		return
+	}
+
+	object["timestamp"] = header.Timestamp
+	object["clock"] = header.Clock
+	for name, value := range fields {
+		object[name] = value
+	}
+	b.write(object)
+}
+
+// errorCode classifica o erro de uma requisição para o programa que usa a
+// ponte, com os mesmos critérios dos códigos de saída.
+func errorCode(err error) string {
+	switch exitCode(err) {
+	case exitUsage:
+		return "usage"
+	case exitUnavailable:
+		return "timeout"
+	}
+	var serverErr *chatsdk.ServerError
+	if errors.As(err, &serverErr) {
+		return "server"
+	}
+	return "error"
+}
+
+// now retorna um cabeçalho com os relógios atuais do cliente, para as
+// operações e eventos locais.
+func (b *bridge) now() chatsdk.Header {
+	return chatsdk.Header{Timestamp: time.Now().UnixMilli(), Clock: b.client.Clock()}
+}
+
+// handle atende uma requisição e escreve a resposta.
+func (b *bridge) handle(req bridgeRequest) {
+	service, header, fields, err := b.dispatch(req)
+	if header.Timestamp == 0 {
+		// Operações locais usam os relógios do cliente
+		header = b.now()
+	}
+	b.respond(req.ID, service, header, fields, err)
+}
+
+// dispatch executa a operação e retorna o serviço da resposta, o cabeçalho da
+// resposta do servidor (zerado nas operações locais) e os campos do resultado.
+func (b *bridge) dispatch(req bridgeRequest) (string, chatsdk.Header, outputFields, error) {
+	client := b.client
+	ctx := b.ctx
+
+	switch req.Op {
+	case chatsdk.ServiceLogin:
+		if req.User == "" {
+			return req.Op, chatsdk.Header{}, nil, usageError(`{"op":"login","user":<nome>}`)
+		}
+		response, err := client.Login(ctx, req.User)
+		if err != nil {
+			return req.Op, chatsdk.Header{}, nil, err
+		}
+		return req.Op, response.Header, outputFields{"user": req.User}, nil
+
+	case chatsdk.ServiceUsers:
+		response, err := client.ListUsers(ctx)
+		if err != nil {
+			return req.Op, chatsdk.Header{}, nil, err
+		}
+		return req.Op, response.Header, outputFields{"users": response.Users}, nil
+
+	case chatsdk.ServiceChannels:
+		response, err := client.ListChannels(ctx)
+		if err != nil {
+			return req.Op, chatsdk.Header{}, nil, err
+		}
+		return req.Op, response.Header, outputFields{"channels": response.Channels}, nil
+
+	case "create":
+		if req.Channel == "" {
+			return req.Op, chatsdk.Header{}, nil, usageError(`{"op":"create","channel":<canal>}`)
+		}
+		response, err := client.CreateChannel(ctx, req.Channel)
+		if err != nil {
+			return req.Op, chatsdk.Header{}, nil, err
+		}
+		return req.Op, response.Header, outputFields{"channel": req.Channel}, nil
+
+	case chatsdk.ServicePublish:
+		if req.Channel == "" || req.Message == "" {
+			return req.Op, chatsdk.Header{}, nil, usageError(`{"op":"publish","channel":<canal>,"message":<texto>}`)
+		}
+		response, err := client.PublishMessageWithMeta(ctx, req.Channel, req.Message, callerMeta(req.Meta))
+		if err != nil {
+			return req.Op, chatsdk.Header{}, nil, err
+		}
+		return req.Op, response.Header, outputFields{"channel": req.Channel, "duplicate": response.Duplicate}, nil
+
+	case chatsdk.ServiceMessage:
+		if req.To == "" || req.Message == "" {
+			return req.Op, chatsdk.Header{}, nil, usageError(`{"op":"message","to":<usuário>,"message":<texto>}`)
+		}
+		response, err := client.SendPrivateMessageWithMeta(ctx, req.To, req.Message, callerMeta(req.Meta))
+		if err != nil {
+			return req.Op, chatsdk.Header{}, nil, err
+		}
+		return req.Op, response.Header, outputFields{"to": req.To, "duplicate": response.Duplicate}, nil
+
+	case "subscribe":
+		if req.Channel == "" {
+			return req.Op, chatsdk.Header{}, nil, usageError(`{"op":"subscribe","channel":<canal>}`)
+		}
+		err := client.Subscribe(req.Channel)
+		return req.Op, chatsdk.Header{}, outputFields{"channel": req.Channel}, err
+
+	case "unsubscribe":
+		if req.Channel == "" {
+			return req.Op, chatsdk.Header{}, nil, usageError(`{"op":"unsubscribe","channel":<canal>}`)
+		}
+		client.Unsubscribe(req.Channel)
+		return req.Op, chatsdk.Header{}, outputFields{"channel": req.Channel}, nil
+
+	case "subscriptions":
+		return req.Op, chatsdk.Header{}, outputFields{"subscriptions": client.Subscriptions()}, nil
+
+	case chatsdk.ServiceHistory:
+		if req.Channel == "" {
+			return req.Op, chatsdk.Header{}, nil, usageError(`{"op":"history","channel":<canal>}`)
+		}
+		response, err := client.History(ctx, req.Channel, req.query())
+		if err != nil {
+			return req.Op, chatsdk.Header{}, nil, err
+		}
+		publications := make([]jsonMessage, 0, len(response.Publications))
+		for _, p := range response.Publications {
+			publications = append(publications, publicationJSON(p))
+		}
+		return req.Op, response.Header, outputFields{
+			"channel":      req.Channel,
+			"publications": publications,
+			"more":         response.More,
+		}, nil
+
+	case chatsdk.ServiceDMHistory:
+		if req.Peer == "" {
+			return req.Op, chatsdk.Header{}, nil, usageError(`{"op":"dm_history","peer":<usuário>}`)
+		}
+		response, err := client.DMHistory(ctx, req.Peer, req.query())
+		if err != nil {
+			return req.Op, chatsdk.Header{}, nil, err
+		}
+		messages := make([]jsonMessage, 0, len(response.Messages))
+		for _, m := range response.Messages {
+			messages = append(messages, privateMessageJSON(m))
+		}
+		return req.Op, response.Header, outputFields{
+			"peer":     req.Peer,
+			"messages": messages,
+			"more":     response.More,
+		}, nil
+
+	case "clock":
+		return req.Op, chatsdk.Header{}, outputFields{"hlc": client.HLC()}, nil
+
+	case "skew":
+		offset, err := client.MeasureServerOffset(ctx, skewSamples)
+		if err != nil {
+			return req.Op, chatsdk.Header{}, nil, err
+		}
+		return req.Op, chatsdk.Header{}, outputFields{
+			"offset_ms": offset.Offset.Milliseconds(),
+			"rtt_us":    offset.RTT.Microseconds(),
+			"samples":   offset.Samples,
+		}, nil
+	}
+
+	return req.Op, chatsdk.Header{}, nil, fmt.Errorf("%w: '%s'", errUnknownCommand, req.Op)
+}
+
+func (r bridgeRequest) query() chatsdk.HistoryQuery {
+	return chatsdk.HistoryQuery{
+		Since:    r.Since,
+		Before:   r.Before,
+		BeforeID: r.BeforeID,
+		Limit:    r.Limit,
+	}
+}
+
+// runBridge executa o modo --bridge até o fim da entrada padrão e retorna o
+// código de saída.
+func runBridge(cfg *config.Config) int {
+	client, err := chatsdk.New(cfg.SDKOptions()...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Erro ao criar cliente:", err)
+		return exitFailure
+	}
+	defer client.Close()
+
+	err = client.Connect()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Erro ao conectar:", err)
+		return exitUnavailable
+	}
+
+	b := &bridge{
+		ctx:     context.Background(),
+		client:  client,
+		encoder: json.NewEncoder(os.Stdout),
+	}
+	b.encoder.SetEscapeHTML(false)
+
+	client.Listen(chatsdk.Handler{
+		Publication: func(p chatsdk.Publication) {
+			b.event(chatsdk.ServicePublication, p.Header, withMeta(outputFields{
+				"channel": p.Channel,
+				"user":    p.User,
+				"message": p.Message,
+			}, p.Meta))
+		},
+		PrivateMessage: func(m chatsdk.PrivateMessage) {
+			b.event(chatsdk.ServicePrivateMessage, m.Header, withMeta(outputFields{
+				"src":     m.Src,
+				"dst":     m.Dst,
+				"message": m.Message,
+			}, m.Meta))
+		},
+		State: func(e chatsdk.ConnectionEvent) {
+			fields := outputFields{"state": e.State.String(), "recovered": e.Recovered}
+			if e.Err != nil {
+				fields["error"] = e.Err.Error()
+			}
+			b.event("connection", b.now(), fields)
+		},
+	})
+
+	// Login automático quando o usuário vem da configuração
+	if cfg.Username != "" {
+		b.handle(bridgeRequest{Op: chatsdk.ServiceLogin, User: cfg.Username})
+	}
+	b.event("ready", b.now(), outputFields{"user": client.Username()})
+
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Buffer(make([]byte, 64*1024), bridgeMaxLine)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var req bridgeRequest
+		err := json.Unmarshal(line, &req)
+		if err != nil {
+			b.respond(nil, "error", chatsdk.Header{}, nil, usageError(fmt.Sprintf("requisição JSON inválida: %v", err)))
+			continue
+		}
+		b.handle(req)
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintln(os.Stderr, "Erro ao ler a entrada:", err)
+		return exitUsage
+	}
+	return exitOK
+}
+
+// callerMeta mantém só o id do meta recebido na entrada. vc e hlc são do
+// SDK: um relógio vetorial escolhido pelo programa seguraria a entrega causal
+// do canal para os outros assinantes até o prazo de espera.
+func callerMeta(meta *chatsdk.Meta) *chatsdk.Meta {
+	if meta == nil || meta.ID == "" {
+		return nil
+	}
+	return &chatsdk.Meta{ID: meta.ID}
+}
+
+// withMeta inclui em fields os metadados do remetente, se houver.
+func withMeta(fields outputFields, meta *chatsdk.Meta) outputFields {
+	if meta != nil {
+		fields["meta"] = meta
+	}
+	return fields
+}
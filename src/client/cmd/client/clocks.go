@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"chat-client/chatsdk"
+	"chat-client/internal/config"
+)
+
+// Quanto as mensagens recebidas esperam, com --order hlc, por outras que
+// ordenem antes delas
+const hlcDisplayDelay = 500 * time.Millisecond
+
+// Requisições feitas pelo comando skew para medir a diferença de relógio
+const skewSamples = 3
+
+// displayQueue exibe as mensagens recebidas. Na ordem de chegada elas são
+// exibidas na hora; com config.OrderHLC, cada uma espera hlcDisplayDelay e
+// são exibidas pelo relógio híbrido do remetente.
+type displayQueue struct {
+	delay time.Duration
+
+	mu    sync.Mutex
+	items []displayItem
+	timer *time.Timer
+}
+
+type displayItem struct {
+	hlc     chatsdk.HLC
+	arrived time.Time
+	show    func()
+}
+
+func newDisplayQueue(order string) *displayQueue {
+	q := &displayQueue{}
+	if order == config.OrderHLC {
+		q.delay = hlcDisplayDelay
+	}
+	return q
+}
+
+// add agenda a exibição de uma mensagem com carimbo hlc.
+func (q *displayQueue) add(hlc chatsdk.HLC, show func()) {
+	if q.delay == 0 {
+		show()
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	i := sort.Search(len(q.items), func(i int) bool {
+		return hlc.Before(q.items[i].hlc)
+	})
+	q.items = slices.Insert(q.items, i, displayItem{hlc: hlc, arrived: time.Now(), show: show})
+	if q.timer == nil {
+		q.timer = time.AfterFunc(q.delay, q.release)
+	}
+}
+
+// release exibe, em ordem, as mensagens que já esperaram o suficiente. A
+// exibição acontece com o lock, para que duas liberações não se intercalem.
+func (q *displayQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	for len(q.items) > 0 && now.Sub(q.items[0].arrived) >= q.delay {
+		q.items[0].show()
+		q.items = q.items[1:]
+	}
+
+	q.timer = nil
+	if len(q.items) > 0 {
+		q.timer = time.AfterFunc(q.items[0].arrived.Add(q.delay).Sub(now), q.release)
+	}
+}
+
+// describeOffset descreve a diferença do relógio local para o do servidor.
+func describeOffset(o chatsdk.ClockOffset) string {
+	skew := o.Offset.Round(time.Millisecond)
+	switch {
+	case skew > 0:
+		return fmt.Sprintf("relógio local %v atrasado em relação ao servidor", skew)
+	case skew < 0:
+		return fmt.Sprintf("relógio local %v adiantado em relação ao servidor", -skew)
+	}
+	return "relógio local sincronizado com o servidor"
+}
+
+// checkSkew avisa quando a diferença para o relógio do servidor passa de
+// maxSkew, uma vez até que ela volte ao limite.
+func (s *shell) checkSkew() {
+	offset, ok := s.client.ServerOffset()
+	if !ok || s.maxSkew <= 0 {
+		return
+	}
+
+	skew := offset.Offset
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew <= s.maxSkew {
+		s.skewWarned.Store(false)
+		return
+	}
+	if !s.skewWarned.Swap(true) {
+		s.emit("skew_warning", chatsdk.Header{}, outputFields{
+			"offset_ms":   offset.Offset.Milliseconds(),
+			"max_skew_ms": s.maxSkew.Milliseconds(),
+		}, func() {
+			fmt.Fprintf(s.out, "*** Aviso: %s (limite %v); horários exibidos podem estar errados. Use 'skew' para detalhes\n",
+				describeOffset(offset), s.maxSkew)
+		})
+	}
+}
+
+// showSkew mede a diferença para o relógio do servidor e mostra os relógios
+// do cliente.
+func (s *shell) showSkew() error {
+	offset, err := s.client.MeasureServerOffset(s.ctx, skewSamples)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	hlc := s.client.HLC()
+	s.emit("skew", chatsdk.Header{}, outputFields{
+		"offset_ms":   offset.Offset.Milliseconds(),
+		"rtt_us":      offset.RTT.Microseconds(),
+		"samples":     offset.Samples,
+		"server_time": now.Add(offset.Offset).UnixMilli(),
+		"hlc":         hlc,
+	}, func() {
+		fmt.Fprintf(s.out, "Diferença: %s\n", describeOffset(offset))
+		fmt.Fprintf(s.out, "  RTT %v, erro máximo ±%v (melhor de %d respostas)\n",
+			offset.RTT.Round(time.Microsecond), (offset.RTT / 2).Round(time.Microsecond), offset.Samples)
+		fmt.Fprintf(s.out, "Horário local: %s; servidor (estimado): %s\n",
+			now.Format("15:04:05.000"), now.Add(offset.Offset).Format("15:04:05.000"))
+		fmt.Fprintf(s.out, "Relógio lógico: %d\n", s.client.Clock())
+		if hlc.Wall > 0 {
+			fmt.Fprintf(s.out, "Relógio híbrido: %v\n", hlc)
+		}
+	})
+	return nil
+}
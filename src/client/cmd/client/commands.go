@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"chat-client/chatsdk"
+	"chat-client/internal/store"
+)
+
+var (
+	errQuit           = errors.New("saindo")
+	errUnknownCommand = errors.New("Comando não reconhecido")
+)
+
+// usageError indica argumentos faltando; é exibido sem o prefixo "Erro:".
+type usageError string
+
+func (e usageError) Error() string {
+	return "Uso: " + string(e)
+}
+
+// Comandos disponíveis, na ordem exibida pela ajuda
+var commandHelp = []struct {
+	usage       string
+	description string
+}{
+	{"login <nome>", "Fazer login"},
+	{"users", "Listar usuários"},
+	{"channels", "Listar canais"},
+	{"create <canal>", "Criar canal"},
+	{"pub <canal> <mensagem>", "Publicar no canal"},
+	{"msg <usuário> <mensagem>", "Enviar mensagem privada"},
+	{"subscribe <canal>", "Receber mensagens do canal"},
+	{"unsubscribe <canal>", "Parar de receber mensagens do canal"},
+	{"subscriptions", "Listar canais assinados"},
+	{"outbox", "Listar mensagens pendentes na fila de saída"},
+	{"history <canal> [n] [before:<horário> [id:<request_id>]]", "Mostrar mensagens anteriores do canal"},
+	{"dmhistory <usuário> [n] [before:<horário> [id:<request_id>]]", "Mostrar mensagens privadas trocadas com o usuário"},
+	{"search <texto> [channel:<canal>] [user:<usuário>] [since:<horário>] [until:<horário>]", "Buscar nas mensagens guardadas"},
+	{"clock", "Mostrar relógio lógico"},
+	{"skew", "Medir a diferença para o relógio do servidor"},
+	{"quit", "Sair"},
+}
+
+// describeConnection descreve as mudanças de estado da assinatura que
+// interessam ao usuário. As novas tentativas de reconexão não são exibidas.
+func describeConnection(e chatsdk.ConnectionEvent) (string, bool) {
+	switch {
+	case e.State == chatsdk.StateDisconnected:
+		return fmt.Sprintf("*** Conexão com o proxy perdida (%v); tentando reconectar", e.Err), true
+	case e.State == chatsdk.StateConnected && !e.Since.IsZero() && e.Err != nil:
+		return fmt.Sprintf("*** Reconectado ao proxy; mensagens publicadas desde %s podem ter sido perdidas (%v)",
+			e.Since.Format(time.TimeOnly), e.Err), true
+	case e.State == chatsdk.StateConnected && !e.Since.IsZero():
+		return fmt.Sprintf("*** Reconectado ao proxy; %d publicações recuperadas desde %s (mensagens privadas podem ter sido perdidas)",
+			e.Recovered, e.Since.Format(time.TimeOnly)), true
+	case e.State == chatsdk.StateConnected && e.Err != nil:
+		return fmt.Sprintf("*** %d publicações perdidas recuperadas do histórico; outras podem ter sido perdidas (%v)",
+			e.Recovered, e.Err), true
+	case e.State == chatsdk.StateConnected && e.Recovered > 0:
+		return fmt.Sprintf("*** %d publicações perdidas recuperadas do histórico", e.Recovered), true
+	}
+	return "", false
+}
+
+// shell executa os comandos do REPL. É usado pelo modo linha e pela TUI,
+// que só mudam para onde a saída é escrita.
+type shell struct {
+	ctx    context.Context
+	client *chatsdk.Client
+	out    io.Writer
+
+	// Estado local do usuário logado (nil antes do login): mensagens
+	// guardadas, se houver diretório configurado, e fila de saída
+	storeDir string
+	storeMu  sync.Mutex
+	store    *store.Store
+	outbox   *chatsdk.Outbox
+
+	// Subcomando ou --script: sem fila de saída nem armazenamento, já que o
+	// processo termina logo em seguida
+	batch bool
+
+	// Exibição das mensagens recebidas (--order)
+	display *displayQueue
+	// Diferença de relógio que gera aviso (0 desativa) e se ele já foi dado
+	maxSkew    time.Duration
+	skewWarned atomic.Bool
+
+	// Formato da saída (--output); outMu impede que os objetos JSON de
+	// goroutines diferentes se misturem
+	output string
+	outMu  sync.Mutex
+}
+
+func (s *shell) printHelp() {
+	commands := make([]outputFields, 0, len(commandHelp))
+	for _, c := range commandHelp {
+		commands = append(commands, outputFields{"usage": c.usage, "description": c.description})
+	}
+	s.emit("help", chatsdk.Header{}, outputFields{"commands": commands}, func() {
+		fmt.Fprintln(s.out, "Comandos disponíveis:")
+		for _, c := range commandHelp {
+			fmt.Fprintf(s.out, "  %s - %s\n", c.usage, c.description)
+		}
+	})
+}
+
+// commandError associa o erro ao comando que o produziu, para a saída JSON.
+type commandError struct {
+	command string
+	err     error
+}
+
+func (e *commandError) Error() string {
+	return e.err.Error()
+}
+
+func (e *commandError) Unwrap() error {
+	return e.err
+}
+
+// report escreve o erro retornado por execute no formato do REPL.
+func (s *shell) report(err error) {
+	if err == nil || err == errQuit {
+		return
+	}
+
+	fields := outputFields{"error": err.Error()}
+	var cmdErr *commandError
+	if errors.As(err, &cmdErr) {
+		fields["command"] = cmdErr.command
+	}
+	s.emit("error", chatsdk.Header{}, fields, func() {
+		fmt.Fprintln(s.out, describeError(err))
+	})
+}
+
+// describeError formata o erro de um comando: erros de uso são exibidos sem
+// o prefixo "Erro:".
+func describeError(err error) string {
+	var usage usageError
+	if errors.As(err, &usage) || errors.Is(err, errUnknownCommand) {
+		return err.Error()
+	}
+	return "Erro: " + err.Error()
+}
+
+// execute roda uma linha de comando. Retorna errQuit para o comando quit;
+// os demais erros identificam o comando (commandError).
+func (s *shell) execute(line string) (err error) {
+	parts := strings.Fields(line)
+	if len(parts) == 0 {
+		return nil
+	}
+
+	defer func() {
+		if err != nil && err != errQuit {
+			err = &commandError{command: parts[0], err: err}
+		}
+	}()
+	defer s.checkSkew()
+
+	client := s.client
+	ctx := s.ctx
+
+	switch parts[0] {
+	case "login":
+		if len(parts) < 2 {
+			return usageError("login <nome>")
+		}
+		response, err := client.Login(ctx, parts[1])
+		if err != nil {
+			return err
+		}
+		s.emit(chatsdk.ServiceLogin, response.Header, outputFields{"user": parts[1]}, func() {
+			fmt.Fprintf(s.out, "Login realizado com sucesso como: %s\n", parts[1])
+		})
+		if s.batch {
+			break
+		}
+		err = s.openSession(parts[1])
+		if err != nil {
+			return err
+		}
+
+	case "users":
+		response, err := client.ListUsers(ctx)
+		if err != nil {
+			return err
+		}
+		s.emit(chatsdk.ServiceUsers, response.Header, outputFields{"users": response.Users}, func() {
+			fmt.Fprintf(s.out, "Usuários: %v\n", response.Users)
+		})
+
+	case "channels":
+		response, err := client.ListChannels(ctx)
+		if err != nil {
+			return err
+		}
+		s.emit(chatsdk.ServiceChannels, response.Header, outputFields{"channels": response.Channels}, func() {
+			fmt.Fprintf(s.out, "Canais: %v\n", response.Channels)
+		})
+
+	case "create":
+		if len(parts) < 2 {
+			return usageError("create <canal>")
+		}
+		response, err := client.CreateChannel(ctx, parts[1])
+		if err != nil {
+			return err
+		}
+		s.emit(chatsdk.ServiceChannel, response.Header, outputFields{"channel": parts[1]}, func() {
+			fmt.Fprintf(s.out, "Canal '%s' criado com sucesso\n", parts[1])
+		})
+
+	case "pub":
+		if len(parts) < 3 {
+			return usageError("pub <canal> <mensagem>")
+		}
+		channel := parts[1]
+		message := strings.Join(parts[2:], " ")
+		header, queued, err := s.publish(channel, message)
+		if err != nil {
+			return err
+		}
+		s.emit(chatsdk.ServicePublish, header, queuedFields(queued, outputFields{
+			"channel": channel,
+			"message": message,
+			"queued":  queued,
+		}), func() {
+			if queued {
+				fmt.Fprintf(s.out, "Broker sem resposta; mensagem para o canal '%s' guardada na fila de saída\n", channel)
+				return
+			}
+			fmt.Fprintf(s.out, "Mensagem publicada no canal '%s'\n", channel)
+		})
+
+	case "msg":
+		if len(parts) < 3 {
+			return usageError("msg <usuário> <mensagem>")
+		}
+		destUser := parts[1]
+		message := strings.Join(parts[2:], " ")
+		header, queued, err := s.sendPrivate(destUser, message)
+		if err != nil {
+			return err
+		}
+		s.emit(chatsdk.ServiceMessage, header, queuedFields(queued, outputFields{
+			"dst":     destUser,
+			"message": message,
+			"queued":  queued,
+		}), func() {
+			if queued {
+				fmt.Fprintf(s.out, "Broker sem resposta; mensagem para '%s' guardada na fila de saída\n", destUser)
+				return
+			}
+			fmt.Fprintf(s.out, "Mensagem enviada para '%s'\n", destUser)
+		})
+
+	case "subscribe":
+		if len(parts) < 2 {
+			return usageError("subscribe <canal>")
+		}
+		err := client.Subscribe(parts[1])
+		if err != nil {
+			return err
+		}
+		s.emit("subscribe", chatsdk.Header{}, outputFields{"channel": parts[1]}, func() {
+			fmt.Fprintf(s.out, "Inscrito no canal '%s'\n", parts[1])
+		})
+
+	case "unsubscribe":
+		if len(parts) < 2 {
+			return usageError("unsubscribe <canal>")
+		}
+		client.Unsubscribe(parts[1])
+		s.emit("unsubscribe", chatsdk.Header{}, outputFields{"channel": parts[1]}, func() {
+			fmt.Fprintf(s.out, "Inscrição no canal '%s' removida\n", parts[1])
+		})
+
+	case "subscriptions":
+		subscriptions := client.Subscriptions()
+		s.emit("subscriptions", chatsdk.Header{}, outputFields{"subscriptions": subscriptions}, func() {
+			fmt.Fprintf(s.out, "Inscrições: %v\n", subscriptions)
+		})
+
+	case "outbox":
+		s.showOutbox()
+
+	case "history":
+		if len(parts) < 2 {
+			return usageError("history <canal> [n] [before:<horário> [id:<request_id>]]")
+		}
+		return s.history(parts[1], parts[2:])
+
+	case "dmhistory":
+		if len(parts) < 2 {
+			return usageError("dmhistory <usuário> [n] [before:<horário> [id:<request_id>]]")
+		}
+		return s.dmHistory(parts[1], parts[2:])
+
+	case "search":
+		if len(parts) < 2 {
+			return usageError("search <texto> [channel:<canal>] [user:<usuário>] [since:<horário>] [until:<horário>]")
+		}
+		return s.search(parts[1:])
+
+	case "clock":
+		hlc := client.HLC()
+		s.emit("clock", chatsdk.Header{}, outputFields{"hlc": hlc}, func() {
+			fmt.Fprintf(s.out, "Relógio lógico: %d\n", client.Clock())
+		})
+
+	case "skew":
+		return s.showSkew()
+
+	case "quit":
+		s.emit("quit", chatsdk.Header{}, nil, func() {
+			fmt.Fprintln(s.out, "Saindo...")
+		})
+		return errQuit
+
+	default:
+		return errUnknownCommand
+	}
+
+	return nil
+}
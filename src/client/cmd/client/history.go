@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"chat-client/chatsdk"
+)
+
+// Mensagens exibidas por history e dmhistory quando n não é informado
+const defaultHistorySize = 20
+
+// parseHistoryArgs lê os argumentos opcionais de history e dmhistory:
+// [n] [before:<timestamp|horário>] [id:<request_id>]. id desempata as
+// mensagens do mesmo milissegundo que before, como no cursor de nextPage.
+func parseHistoryArgs(args []string) (chatsdk.HistoryQuery, error) {
+	q := chatsdk.HistoryQuery{Limit: defaultHistorySize}
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "before:"):
+			before, err := parseTimeArg(strings.TrimPrefix(arg, "before:"))
+			if err != nil {
+				return q, err
+			}
+			q.Before = before
+		case strings.HasPrefix(arg, "id:"):
+			q.BeforeID = strings.TrimPrefix(arg, "id:")
+		default:
+			n, err := strconv.Atoi(arg)
+			if err != nil || n <= 0 {
+				return q, fmt.Errorf("quantidade inválida: %s", arg)
+			}
+			q.Limit = n
+		}
+	}
+	if q.BeforeID != "" && q.Before == 0 {
+		return q, fmt.Errorf("id: só vale junto com before:")
+	}
+	return q, nil
+}
+
+// parseTimeArg aceita um timestamp em milissegundos, uma data e hora
+// (2006-01-02T15:04:05), uma data (2006-01-02) ou um horário de hoje
+// (15:04:05), no fuso local.
+func parseTimeArg(value string) (int64, error) {
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return ms, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02T15:04:05", value, time.Local); err == nil {
+		return t.UnixMilli(), nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return t.UnixMilli(), nil
+	}
+	if t, err := time.ParseInLocation(time.TimeOnly, value, time.Local); err == nil {
+		now := time.Now()
+		today := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.Local)
+		return today.UnixMilli(), nil
+	}
+	return 0, fmt.Errorf("horário inválido: %s", value)
+}
+
+// nextPage monta o comando que busca as mensagens anteriores à página
+// exibida. O cursor é o timestamp da mais antiga com o request_id, que
+// desempata as do mesmo milissegundo; o clock não serve, já que cada réplica
+// atribui o seu.
+func nextPage(command, target string, q chatsdk.HistoryQuery, oldest chatsdk.Header) string {
+	next := q.NextPage(oldest)
+	cursor := fmt.Sprintf("before:%d", next.Before)
+	if next.BeforeID != "" {
+		cursor += " id:" + next.BeforeID
+	}
+	return fmt.Sprintf("%s %s %d %s", command, target, q.Limit, cursor)
+}
+
+func (s *shell) history(channel string, args []string) error {
+	return s.channelHistory("history", channel, args)
+}
+
+// channelHistory busca e exibe a página do histórico do canal pedida por
+// args, como no REPL. command é o comando, sem o canal, indicado para buscar
+// a página anterior.
+func (s *shell) channelHistory(command, channel string, args []string) error {
+	q, err := parseHistoryArgs(args)
+	if err != nil {
+		return err
+	}
+	response, err := s.client.History(s.ctx, channel, q)
+	if err != nil {
+		return err
+	}
+	s.emitHistory(command, channel, q, response)
+	return nil
+}
+
+// emitHistory exibe uma página do histórico do canal e o comando que busca a
+// página anterior.
+func (s *shell) emitHistory(command, channel string, q chatsdk.HistoryQuery, response *chatsdk.HistoryResponse) {
+	publications := make([]jsonMessage, 0, len(response.Publications))
+	for _, p := range response.Publications {
+		publications = append(publications, publicationJSON(p))
+	}
+	fields := outputFields{
+		"channel":      channel,
+		"publications": publications,
+		"more":         response.More,
+	}
+	if response.More {
+		fields["next"] = nextPage(command, channel, q, response.Publications[0].Header)
+	}
+
+	s.emit(chatsdk.ServiceHistory, response.Header, fields, func() {
+		if len(response.Publications) == 0 {
+			fmt.Fprintf(s.out, "Nenhuma mensagem no histórico do canal '%s'\n", channel)
+			return
+		}
+		for _, p := range response.Publications {
+			fmt.Fprintf(s.out, "[%s] %s: %s\n", p.Channel, p.User, p.Message)
+		}
+		if next, ok := fields["next"]; ok {
+			fmt.Fprintf(s.out, "Mensagens anteriores: %s\n", next)
+		}
+	})
+}
+
+func (s *shell) dmHistory(peer string, args []string) error {
+	q, err := parseHistoryArgs(args)
+	if err != nil {
+		return err
+	}
+	response, err := s.client.DMHistory(s.ctx, peer, q)
+	if err != nil {
+		return err
+	}
+
+	messages := make([]jsonMessage, 0, len(response.Messages))
+	for _, m := range response.Messages {
+		messages = append(messages, privateMessageJSON(m))
+	}
+	fields := outputFields{
+		"peer":     peer,
+		"messages": messages,
+		"more":     response.More,
+	}
+	if response.More {
+		fields["next"] = nextPage("dmhistory", peer, q, response.Messages[0].Header)
+	}
+
+	s.emit(chatsdk.ServiceDMHistory, response.Header, fields, func() {
+		if len(response.Messages) == 0 {
+			fmt.Fprintf(s.out, "Nenhuma mensagem privada trocada com '%s'\n", peer)
+			return
+		}
+		for _, m := range response.Messages {
+			fmt.Fprintf(s.out, "[PRIVADO] %s: %s\n", m.Src, m.Message)
+		}
+		if response.More {
+			fmt.Fprintf(s.out, "Mensagens anteriores: %s\n", fields["next"])
+		}
+	})
+	return nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"chat-client/chatsdk"
+	"chat-client/internal/config"
+)
+
+func main() {
+	// client <subcomando> [flags] executa um único comando e sai
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		os.Exit(runSubcommand(os.Args[1], os.Args[2:]))
+	}
+
+	tui := flag.Bool("tui", false, "interface de tela cheia com lista de conversas")
+	script := flag.String("script", "", "executa os comandos do arquivo (- para a entrada padrão) e sai; o código de saída indica a falha")
+	bridgeMode := flag.Bool("bridge", false, "ponte JSON: requisições em linhas JSON na entrada padrão, respostas e eventos na saída padrão")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Uso: client [flags] | client <subcomando> [flags]")
+		flag.PrintDefaults()
+		printSubcommands(os.Stderr)
+	}
+
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Fatal("Erro na configuração:", err)
+	}
+
+	if *script != "" {
+		os.Exit(runScript(cfg, *script))
+	}
+	if *bridgeMode {
+		os.Exit(runBridge(cfg))
+	}
+
+	client, err := chatsdk.New(cfg.SDKOptions()...)
+	if err != nil {
+		log.Fatal("Erro ao criar cliente:", err)
+	}
+	defer client.Close()
+
+	err = client.Connect()
+	if err != nil {
+		log.Fatal("Erro ao conectar:", err)
+	}
+
+	sh := &shell{
+		ctx:      context.Background(),
+		client:   client,
+		out:      os.Stdout,
+		storeDir: cfg.StoreDir,
+		display:  newDisplayQueue(cfg.Order),
+		maxSkew:  cfg.MaxSkew,
+		output:   cfg.Output,
+	}
+	defer sh.closeSession()
+
+	if *tui {
+		// A TUI tem seu próprio formato; --output vale para o modo linha
+		sh.output = config.OutputText
+		err = runTUI(sh, cfg)
+		if err != nil {
+			log.Fatal("Erro na interface:", err)
+		}
+		return
+	}
+
+	runLineMode(sh, cfg)
+}
+
+// runLineMode é o REPL simples em stdin/stdout, adequado para scripts. Com
+// --output json ou jsonl, a saída tem só os objetos JSON, sem prompt nem
+// textos de apresentação.
+func runLineMode(sh *shell, cfg *config.Config) {
+	interactive := !sh.structured()
+	if interactive {
+		fmt.Println("Conectado ao sistema de mensagens")
+	}
+
+	// Iniciar escuta de mensagens
+	sh.client.Listen(chatsdk.Handler{
+		Publication: func(p chatsdk.Publication) {
+			sh.display.add(p.HLC(), func() {
+				sh.showPublication(p)
+			})
+			sh.record(&p)
+		},
+		PrivateMessage: func(m chatsdk.PrivateMessage) {
+			sh.display.add(m.HLC(), func() {
+				sh.showPrivateMessage(m)
+			})
+			sh.record(&m)
+		},
+		State: func(e chatsdk.ConnectionEvent) {
+			sh.showConnection(e)
+			if e.State == chatsdk.StateConnected {
+				sh.wakeOutbox()
+			}
+		},
+	})
+
+	// Login automático quando o usuário vem da configuração
+	if cfg.Username != "" {
+		sh.report(sh.execute("login " + cfg.Username))
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	if interactive {
+		fmt.Println("=== Sistema de Mensagens Distribuído ===")
+		sh.printHelp()
+		fmt.Println()
+	}
+
+	for {
+		if interactive {
+			fmt.Print("> ")
+		}
+		if !scanner.Scan() {
+			break
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		err := sh.execute(line)
+		if err == errQuit {
+			return
+		}
+		sh.report(err)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"chat-client/chatsdk"
+	"chat-client/internal/config"
+)
+
+// outputFields são os campos de um objeto da saída JSON, além de service,
+// timestamp e clock.
+type outputFields map[string]any
+
+// emit escreve o resultado de um comando ou uma mensagem recebida. Na saída
+// de texto chama text (que pode ser nil); em json e jsonl escreve um objeto
+// com service, o timestamp e o clock de header (os atuais do cliente, se
+// header estiver zerado, como nos comandos locais) e fields. Campos nil em
+// fields são omitidos, inclusive timestamp e clock.
+func (s *shell) emit(service string, header chatsdk.Header, fields outputFields, text func()) {
+	s.outMu.Lock()
+	defer s.outMu.Unlock()
+
+	if !s.structured() {
+		if text != nil {
+			text()
+		}
+		return
+	}
+
+	object := outputFields{
+		"service":   service,
+		"timestamp": header.Timestamp,
+		"clock":     header.Clock,
+	}
+	if header.Timestamp == 0 {
+		object["timestamp"] = time.Now().UnixMilli()
+	}
+	if header.Clock == 0 {
+		object["clock"] = s.client.Clock()
+	}
+	for name, value := range fields {
+		if value == nil {
+			delete(object, name)
+			continue
+		}
+		object[name] = value
+	}
+
+	encoder := json.NewEncoder(s.out)
+	encoder.SetEscapeHTML(false)
+	if s.output == config.OutputJSON {
+		encoder.SetIndent("", "  ")
+	}
+	encoder.Encode(object)
+}
+
+// queuedFields omite timestamp e clock do resultado de uma mensagem que
+// ficou na fila de saída: ela ainda não tem os do servidor, e os locais
+// não seriam os dela.
+func queuedFields(queued bool, fields outputFields) outputFields {
+	if queued {
+		fields["timestamp"] = nil
+		fields["clock"] = nil
+	}
+	return fields
+}
+
+// structured indica se a saída é json ou jsonl.
+func (s *shell) structured() bool {
+	return s.output == config.OutputJSON || s.output == config.OutputJSONL
+}
+
+// jsonMessage é a forma das publicações e mensagens privadas dentro dos
+// resultados JSON (history, dmhistory).
+type jsonMessage struct {
+	Channel   string `json:"channel,omitempty"`
+	User      string `json:"user,omitempty"`
+	Src       string `json:"src,omitempty"`
+	Dst       string `json:"dst,omitempty"`
+	Message   string `json:"message"`
+	Timestamp int64  `json:"timestamp"`
+	Clock     int64  `json:"clock"`
+	// Identifica o item no histórico, para o cursor das páginas
+	RequestID string `json:"request_id,omitempty"`
+	// Metadados do remetente (id, relógios), quando enviados
+	Meta *chatsdk.Meta `json:"meta,omitempty"`
+}
+
+func publicationJSON(p chatsdk.Publication) jsonMessage {
+	return jsonMessage{
+		Channel:   p.Channel,
+		User:      p.User,
+		Message:   p.Message,
+		Timestamp: p.Timestamp,
+		Clock:     p.Clock,
+		RequestID: p.RequestID,
+		Meta:      p.Meta,
+	}
+}
+
+func privateMessageJSON(m chatsdk.PrivateMessage) jsonMessage {
+	return jsonMessage{
+		Src:       m.Src,
+		Dst:       m.Dst,
+		Message:   m.Message,
+		Timestamp: m.Timestamp,
+		Clock:     m.Clock,
+		RequestID: m.RequestID,
+		Meta:      m.Meta,
+	}
+}
+
+// showPublication exibe uma publicação recebida.
+func (s *shell) showPublication(p chatsdk.Publication) {
+	s.emit(chatsdk.ServicePublication, p.Header, outputFields{
+		"channel": p.Channel,
+		"user":    p.User,
+		"message": p.Message,
+	}, func() {
+		fmt.Fprintf(s.out, "[%s] %s: %s\n", p.Channel, p.User, p.Message)
+	})
+}
+
+// showPrivateMessage exibe uma mensagem privada recebida.
+func (s *shell) showPrivateMessage(m chatsdk.PrivateMessage) {
+	s.emit(chatsdk.ServicePrivateMessage, m.Header, outputFields{
+		"src":     m.Src,
+		"dst":     m.Dst,
+		"message": m.Message,
+	}, func() {
+		fmt.Fprintf(s.out, "[PRIVADO] %s: %s\n", m.Src, m.Message)
+	})
+}
+
+// showConnection exibe as mudanças de estado da assinatura que interessam ao
+// usuário (veja describeConnection).
+func (s *shell) showConnection(e chatsdk.ConnectionEvent) {
+	text, ok := describeConnection(e)
+	if !ok {
+		return
+	}
+	fields := outputFields{
+		"state":     e.State.String(),
+		"recovered": e.Recovered,
+	}
+	if !e.Since.IsZero() {
+		fields["since"] = e.Since.UnixMilli()
+	}
+	if e.Err != nil {
+		fields["error"] = e.Err.Error()
+	}
+	s.emit("connection", chatsdk.Header{}, fields, func() {
+		fmt.Fprintln(s.out, text)
+	})
+}
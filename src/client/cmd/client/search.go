@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"chat-client/chatsdk"
+	"chat-client/internal/store"
+)
+
+// Resultados exibidos por search, os mais recentes
+const searchLimit = 50
+
+// parseSearchArgs separa os filtros channel:, user:, since: e until: do texto
+// procurado.
+func parseSearchArgs(args []string) (store.Query, error) {
+	q := store.Query{Limit: searchLimit}
+	var words []string
+	for _, arg := range args {
+		name, value, ok := strings.Cut(arg, ":")
+		if !ok || value == "" {
+			words = append(words, arg)
+			continue
+		}
+
+		switch name {
+		case "channel":
+			q.Channel = value
+		case "user":
+			q.User = value
+		case "since", "until":
+			ms, err := parseTimeArg(value)
+			if err != nil {
+				return q, err
+			}
+			if name == "since" {
+				q.Since = time.UnixMilli(ms)
+			} else {
+				q.Until = time.UnixMilli(ms)
+			}
+		default:
+			words = append(words, arg)
+		}
+	}
+	q.Text = strings.Join(words, " ")
+	return q, nil
+}
+
+func (s *shell) search(args []string) error {
+	q, err := parseSearchArgs(args)
+	if err != nil {
+		return err
+	}
+
+	s.storeMu.Lock()
+	st := s.store
+	var entries []store.Entry
+	var truncated bool
+	if st != nil {
+		entries, truncated, err = st.Search(q)
+	}
+	s.storeMu.Unlock()
+
+	if st == nil {
+		if s.storeDir == "" {
+			return fmt.Errorf("armazenamento de mensagens desativado")
+		}
+		return fmt.Errorf("faça login para buscar nas mensagens guardadas")
+	}
+	if err != nil {
+		return err
+	}
+
+	if entries == nil {
+		entries = []store.Entry{}
+	}
+	s.emit("search", chatsdk.Header{}, outputFields{
+		"entries":   entries,
+		"truncated": truncated,
+	}, func() {
+		if len(entries) == 0 {
+			fmt.Fprintln(s.out, "Nenhuma mensagem encontrada")
+			return
+		}
+		for _, e := range entries {
+			when := e.Time().Format(time.DateTime)
+			if e.Private() {
+				fmt.Fprintf(s.out, "%s [PRIVADO] %s → %s: %s\n", when, e.User, e.Dst, e.Message)
+			} else {
+				fmt.Fprintf(s.out, "%s [%s] %s: %s\n", when, e.Channel, e.User, e.Message)
+			}
+		}
+		if truncated {
+			fmt.Fprintf(s.out, "Mostrando as %d mais recentes; use since: e until: para restringir\n", searchLimit)
+		}
+	})
+	return nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"chat-client/chatsdk"
+	"chat-client/internal/store"
+)
+
+// openSession prepara o estado local do usuário que acabou de fazer login:
+// o armazenamento de mensagens (se houver diretório configurado) e a fila de
+// saída, que retoma os itens pendentes de execuções anteriores.
+func (s *shell) openSession(username string) error {
+	s.closeSession()
+
+	var st *store.Store
+	var err error
+	if s.storeDir != "" {
+		st, err = store.Open(s.storeDir, username)
+		if err != nil {
+			err = fmt.Errorf("mensagens não serão guardadas: %w", err)
+		}
+	}
+
+	// Sem armazenamento a fila fica só em memória
+	var outboxStore chatsdk.OutboxStore
+	if st != nil {
+		outboxStore = st
+	}
+	outbox, outboxErr := chatsdk.NewOutbox(s.client, outboxStore, chatsdk.OutboxHandler{
+		Sent:   s.outboxSent,
+		Failed: s.outboxFailed,
+	})
+	if outboxErr != nil && err == nil {
+		err = outboxErr
+	}
+
+	s.storeMu.Lock()
+	s.store = st
+	s.outbox = outbox
+	s.storeMu.Unlock()
+
+	if pending := len(s.pendingItems()); pending > 0 {
+		s.emit("outbox_pending", chatsdk.Header{}, outputFields{"count": pending}, func() {
+			fmt.Fprintf(s.out, "%d mensagens pendentes na fila de saída serão reenviadas\n", pending)
+		})
+	}
+	return err
+}
+
+// closeSession para a fila de saída e fecha o armazenamento. A fila é
+// fechada fora do lock porque seus callbacks também gravam no armazenamento.
+func (s *shell) closeSession() {
+	s.storeMu.Lock()
+	st, outbox := s.store, s.outbox
+	s.store, s.outbox = nil, nil
+	s.storeMu.Unlock()
+
+	if outbox != nil {
+		outbox.Close()
+	}
+	if st != nil {
+		st.Close()
+	}
+}
+
+// record guarda uma mensagem recebida ou enviada. Falhas só são registradas
+// no log, para não interromper a exibição.
+func (s *shell) record(p chatsdk.Payload) {
+	s.storeMu.Lock()
+	defer s.storeMu.Unlock()
+	if s.store == nil {
+		return
+	}
+
+	var err error
+	switch m := p.(type) {
+	case *chatsdk.Publication:
+		err = s.store.AddPublication(*m)
+	case *chatsdk.PrivateMessage:
+		err = s.store.AddPrivateMessage(*m)
+	}
+	if err != nil {
+		log.Printf("Erro ao guardar mensagem: %v", err)
+	}
+}
+
+func (s *shell) currentOutbox() *chatsdk.Outbox {
+	s.storeMu.Lock()
+	defer s.storeMu.Unlock()
+	return s.outbox
+}
+
+func (s *shell) pendingItems() []chatsdk.OutboxItem {
+	if outbox := s.currentOutbox(); outbox != nil {
+		return outbox.Pending()
+	}
+	return nil
+}
+
+// wakeOutbox antecipa o reenvio da fila quando a conexão volta.
+func (s *shell) wakeOutbox() {
+	if outbox := s.currentOutbox(); outbox != nil {
+		outbox.Wake()
+	}
+}
+
+// publish publica pela fila de saída, que guarda a mensagem se o broker não
+// responder, e retorna o cabeçalho da resposta (zerado se a mensagem ficou
+// na fila). Antes do login não há fila e o erro é retornado.
+func (s *shell) publish(channel, message string) (header chatsdk.Header, queued bool, err error) {
+	if outbox := s.currentOutbox(); outbox != nil {
+		return outbox.Publish(s.ctx, channel, message)
+	}
+	response, err := s.client.PublishMessage(s.ctx, channel, message)
+	if err != nil {
+		return header, false, err
+	}
+	return response.Header, false, nil
+}
+
+// sendPrivate envia pela fila de saída e guarda a mensagem, já que o servidor
+// só publica mensagens privadas para o destinatário.
+func (s *shell) sendPrivate(user, message string) (header chatsdk.Header, queued bool, err error) {
+	if outbox := s.currentOutbox(); outbox != nil {
+		header, queued, err = outbox.SendPrivate(s.ctx, user, message)
+	} else {
+		var response *chatsdk.MessageResponse
+		response, err = s.client.SendPrivateMessage(s.ctx, user, message)
+		if err == nil {
+			header = response.Header
+		}
+	}
+	if err != nil || queued {
+		return header, queued, err
+	}
+	s.recordSent(user, message, time.UnixMilli(header.Timestamp))
+	return header, false, nil
+}
+
+func (s *shell) recordSent(user, message string, when time.Time) {
+	s.record(&chatsdk.PrivateMessage{
+		Src:     s.client.Username(),
+		Dst:     user,
+		Message: message,
+		Header:  chatsdk.Header{Timestamp: when.UnixMilli()},
+	})
+}
+
+func describeItem(item chatsdk.OutboxItem) string {
+	if item.Service == chatsdk.ServiceMessage {
+		return fmt.Sprintf("para '%s'", item.Target)
+	}
+	return fmt.Sprintf("no canal '%s'", item.Target)
+}
+
+func (s *shell) outboxSent(item chatsdk.OutboxItem) {
+	s.emit("outbox_sent", chatsdk.Header{}, outputFields{"item": item}, func() {
+		fmt.Fprintf(s.out, "*** Mensagem pendente enviada %s: %s\n", describeItem(item), item.Message)
+	})
+	if item.Service == chatsdk.ServiceMessage {
+		s.recordSent(item.Target, item.Message, time.Now())
+	}
+}
+
+func (s *shell) outboxFailed(item chatsdk.OutboxItem, err error) {
+	s.emit("outbox_failed", chatsdk.Header{}, outputFields{"item": item, "error": err.Error()}, func() {
+		fmt.Fprintf(s.out, "*** Mensagem pendente %s descartada (%v): %s\n", describeItem(item), err, item.Message)
+	})
+}
+
+// showOutbox lista os itens aguardando envio.
+func (s *shell) showOutbox() {
+	items := s.pendingItems()
+	if items == nil {
+		items = []chatsdk.OutboxItem{}
+	}
+	s.emit("outbox", chatsdk.Header{}, outputFields{"pending": items}, func() {
+		if len(items) == 0 {
+			fmt.Fprintln(s.out, "Nenhuma mensagem pendente")
+			return
+		}
+		fmt.Fprintf(s.out, "Mensagens pendentes (%d):\n", len(items))
+		for _, item := range items {
+			fmt.Fprintf(s.out, "  %s %s: %s", item.Created.Format(time.TimeOnly), describeItem(item), item.Message)
+			if item.Attempts > 0 {
+				fmt.Fprintf(s.out, " (tentativas: %d; último erro: %s)", item.Attempts, item.LastError)
+			}
+			fmt.Fprintln(s.out)
+		}
+	})
+}
@@ -0,0 +1,393 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"chat-client/chatsdk"
+	"chat-client/internal/config"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Conversa com a saída dos comandos e os logs do SDK
+const statusKey = "status"
+
+// conversation guarda as linhas de um canal ("#canal"), de uma conversa
+// privada ("@usuário") ou do painel de status.
+type conversation struct {
+	lines  []string
+	unread int
+}
+
+// tui é a interface de tela cheia. Os campos abaixo de input só são
+// acessados pela goroutine da interface; as demais goroutines usam post.
+type tui struct {
+	app      *tview.Application
+	sidebar  *tview.List
+	messages *tview.TextView
+	input    *tview.InputField
+	shell    *shell
+
+	// stopped evita enfileirar atualizações depois que a interface terminou
+	stopped atomic.Bool
+
+	conversations map[string]*conversation
+	order         []string
+	active        string
+}
+
+// statusWriter envia para o painel de status tudo o que os comandos do shell
+// (e o pacote log) escreveriam no terminal.
+type statusWriter struct {
+	t *tui
+}
+
+func (w statusWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		w.t.post(statusKey, tview.Escape(line))
+	}
+	return len(p), nil
+}
+
+func runTUI(sh *shell, cfg *config.Config) error {
+	t := &tui{
+		app:           tview.NewApplication(),
+		sidebar:       tview.NewList(),
+		messages:      tview.NewTextView(),
+		input:         tview.NewInputField(),
+		shell:         sh,
+		conversations: make(map[string]*conversation),
+	}
+
+	t.sidebar.ShowSecondaryText(false).
+		SetSelectedFunc(func(i int, _, _ string, _ rune) {
+			t.switchTo(t.order[i])
+			t.app.SetFocus(t.input)
+		}).
+		SetBorder(true).
+		SetTitle(" Conversas ")
+
+	t.messages.SetDynamicColors(true).
+		SetScrollable(true).
+		SetWrap(true).
+		SetBorder(true)
+
+	t.input.SetLabel("> ").
+		SetDoneFunc(func(key tcell.Key) {
+			if key != tcell.KeyEnter {
+				return
+			}
+			text := strings.TrimSpace(t.input.GetText())
+			t.input.SetText("")
+			if text != "" {
+				t.submit(text)
+			}
+		})
+
+	chat := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(t.messages, 0, 1, false).
+		AddItem(t.input, 1, 0, true)
+	layout := tview.NewFlex().
+		AddItem(t.sidebar, 24, 0, false).
+		AddItem(chat, 0, 1, true)
+
+	// Tab alterna o foco; Ctrl-N/Ctrl-P trocam de conversa
+	t.app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyTab:
+			if t.input.HasFocus() {
+				t.app.SetFocus(t.sidebar)
+			} else {
+				t.app.SetFocus(t.input)
+			}
+			return nil
+		case tcell.KeyCtrlN:
+			t.cycle(1)
+			return nil
+		case tcell.KeyCtrlP:
+			t.cycle(-1)
+			return nil
+		}
+		return event
+	})
+
+	// A saída dos comandos e os logs do SDK vão para o painel de status
+	sh.out = statusWriter{t}
+	log.SetOutput(statusWriter{t})
+
+	t.open(statusKey)
+	t.switchTo(statusKey)
+	t.appendLine(statusKey, "Conectado ao sistema de mensagens")
+	t.appendLine(statusKey, "Comandos começam com /, por exemplo /join geral ou /msg bob oi. /help lista todos.")
+
+	sh.client.Listen(chatsdk.Handler{
+		Publication: func(p chatsdk.Publication) {
+			sh.display.add(p.HLC(), func() {
+				t.post("#"+p.Channel, formatLine(p.Timestamp, p.User, p.Message))
+			})
+			sh.record(&p)
+		},
+		PrivateMessage: func(m chatsdk.PrivateMessage) {
+			sh.display.add(m.HLC(), func() {
+				t.post("@"+m.Src, formatLine(m.Timestamp, m.Src, m.Message))
+			})
+			sh.record(&m)
+		},
+		State: func(e chatsdk.ConnectionEvent) {
+			if text, ok := describeConnection(e); ok {
+				t.notify("[red]" + tview.Escape(text) + "[-]")
+			}
+			if e.State == chatsdk.StateConnected {
+				sh.wakeOutbox()
+			}
+		},
+	})
+
+	// Login automático quando o usuário vem da configuração
+	if cfg.Username != "" {
+		t.run("login " + cfg.Username)
+	}
+
+	err := t.app.SetRoot(layout, true).SetFocus(t.input).Run()
+	t.stopped.Store(true)
+	log.SetOutput(os.Stderr)
+	return err
+}
+
+func formatLine(timestamp int64, user, message string) string {
+	clock := time.UnixMilli(timestamp).Format("15:04:05")
+	return fmt.Sprintf("[gray]%s[-] [yellow]%s[-]: %s", clock, tview.Escape(user), tview.Escape(message))
+}
+
+// post agenda a inclusão de uma linha a partir de qualquer goroutine.
+func (t *tui) post(key, line string) {
+	if t.stopped.Load() {
+		return
+	}
+	t.app.QueueUpdateDraw(func() {
+		t.appendLine(key, line)
+	})
+}
+
+// notify mostra o aviso no status e na conversa ativa.
+func (t *tui) notify(line string) {
+	if t.stopped.Load() {
+		return
+	}
+	t.app.QueueUpdateDraw(func() {
+		t.appendLine(statusKey, line)
+		if t.active != statusKey {
+			t.appendLine(t.active, line)
+		}
+	})
+}
+
+// open cria a conversa (e o item na lista lateral) se ainda não existir.
+func (t *tui) open(key string) *conversation {
+	conv, ok := t.conversations[key]
+	if ok {
+		return conv
+	}
+
+	conv = &conversation{}
+	t.conversations[key] = conv
+	t.order = append(t.order, key)
+	t.sidebar.AddItem(key, "", 0, nil)
+	return conv
+}
+
+// close remove a conversa e volta para o status se ela estava ativa. O
+// status nunca é removido.
+func (t *tui) close(key string) {
+	if key == statusKey {
+		return
+	}
+	for i, k := range t.order {
+		if k == key {
+			t.order = append(t.order[:i], t.order[i+1:]...)
+			t.sidebar.RemoveItem(i)
+			break
+		}
+	}
+	delete(t.conversations, key)
+	if t.active == key {
+		t.switchTo(statusKey)
+	}
+}
+
+func (t *tui) appendLine(key, line string) {
+	conv := t.open(key)
+	conv.lines = append(conv.lines, line)
+	if key == t.active {
+		fmt.Fprintln(t.messages, line)
+		t.messages.ScrollToEnd()
+		return
+	}
+	conv.unread++
+	t.refreshSidebar()
+}
+
+func (t *tui) switchTo(key string) {
+	conv := t.open(key)
+	t.active = key
+	conv.unread = 0
+
+	t.messages.Clear()
+	t.messages.SetTitle(" " + key + " ")
+	for _, line := range conv.lines {
+		fmt.Fprintln(t.messages, line)
+	}
+	t.messages.ScrollToEnd()
+	t.refreshSidebar()
+}
+
+func (t *tui) cycle(step int) {
+	for i, key := range t.order {
+		if key == t.active {
+			next := (i + step + len(t.order)) % len(t.order)
+			t.switchTo(t.order[next])
+			return
+		}
+	}
+}
+
+// refreshSidebar atualiza os contadores de não lidas e a seleção.
+func (t *tui) refreshSidebar() {
+	for i, key := range t.order {
+		label := key
+		if unread := t.conversations[key].unread; unread > 0 {
+			label = fmt.Sprintf("%s [red](%d)[-]", key, unread)
+		}
+		t.sidebar.SetItemText(i, label, "")
+		if key == t.active {
+			t.sidebar.SetCurrentItem(i)
+		}
+	}
+}
+
+// syncChannels abre uma conversa para cada canal assinado e fecha as de
+// canais que deixaram de ser assinados.
+func (t *tui) syncChannels() {
+	inbox := t.shell.client.Username()
+	subscribed := make(map[string]bool)
+	for _, topic := range t.shell.client.Subscriptions() {
+		if topic == inbox {
+			continue
+		}
+		subscribed["#"+topic] = true
+		t.open("#" + topic)
+	}
+	for _, key := range append([]string(nil), t.order...) {
+		if strings.HasPrefix(key, "#") && !subscribed[key] {
+			t.close(key)
+		}
+	}
+	t.refreshSidebar()
+}
+
+// run executa um comando do shell fora da goroutine da interface, já que as
+// requisições podem esperar pelas retentativas.
+func (t *tui) run(command string) {
+	go func() {
+		err := t.shell.execute(command)
+		if err == errQuit {
+			t.app.Stop()
+			return
+		}
+		t.shell.report(err)
+		if !t.stopped.Load() {
+			t.app.QueueUpdateDraw(t.syncChannels)
+		}
+	}()
+}
+
+// submit trata o texto digitado: comandos começam com "/", o resto é enviado
+// para a conversa ativa.
+func (t *tui) submit(text string) {
+	if strings.HasPrefix(text, "/") {
+		t.command(strings.TrimPrefix(text, "/"))
+		return
+	}
+
+	switch {
+	case strings.HasPrefix(t.active, "#"):
+		t.run("pub " + strings.TrimPrefix(t.active, "#") + " " + text)
+	case strings.HasPrefix(t.active, "@"):
+		t.sendPrivate(strings.TrimPrefix(t.active, "@"), text)
+	default:
+		// No painel de status o texto é um comando, com ou sem "/"
+		t.command(text)
+	}
+}
+
+func (t *tui) command(text string) {
+	parts := strings.Fields(text)
+	if len(parts) == 0 {
+		return
+	}
+
+	switch parts[0] {
+	case "help":
+		t.shell.printHelp()
+		fmt.Fprintln(t.shell.out, "  join <canal> - Assinar e abrir o canal")
+		fmt.Fprintln(t.shell.out, "  leave - Sair do canal ou fechar a conversa privada ativa")
+		fmt.Fprintln(t.shell.out, "  Tab alterna o foco, Ctrl-N/Ctrl-P trocam de conversa")
+	case "join":
+		if len(parts) < 2 {
+			t.shell.report(usageError("join <canal>"))
+			return
+		}
+		err := t.shell.client.Subscribe(parts[1])
+		if err != nil {
+			t.shell.report(err)
+			return
+		}
+		t.switchTo("#" + parts[1])
+	case "leave":
+		if strings.HasPrefix(t.active, "@") {
+			t.close(t.active)
+			return
+		}
+		if !strings.HasPrefix(t.active, "#") {
+			t.shell.report(fmt.Errorf("leave só fecha canais e conversas privadas"))
+			return
+		}
+		t.shell.client.Unsubscribe(strings.TrimPrefix(t.active, "#"))
+		t.syncChannels()
+	case "msg":
+		if len(parts) < 2 {
+			t.shell.report(usageError("msg <usuário> [mensagem]"))
+			return
+		}
+		t.switchTo("@" + parts[1])
+		if len(parts) > 2 {
+			t.sendPrivate(parts[1], strings.Join(parts[2:], " "))
+		}
+	default:
+		t.run(text)
+	}
+}
+
+// sendPrivate envia a mensagem e a mostra na conversa, já que o servidor só
+// publica mensagens privadas para o destinatário.
+func (t *tui) sendPrivate(user, message string) {
+	go func() {
+		header, queued, err := t.shell.sendPrivate(user, message)
+		if err != nil {
+			t.shell.report(err)
+			t.post("@"+user, "[red]não enviada:[-] "+tview.Escape(message))
+			return
+		}
+		if queued {
+			t.post("@"+user, "[yellow]pendente:[-] "+tview.Escape(message))
+			return
+		}
+		t.post("@"+user, formatLine(header.Timestamp, t.shell.client.Username(), message))
+	}()
+}
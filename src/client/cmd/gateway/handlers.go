@@ -0,0 +1,534 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"chat-client/chatsdk"
+)
+
+// Tamanho máximo do corpo de uma requisição
+const maxBody = 1 << 20
+
+// Intervalo dos comentários enviados nos streams parados, para que proxies
+// HTTP não fechem a conexão
+const streamKeepAlive = 15 * time.Second
+
+// fields são os campos de uma resposta ou evento JSON.
+type fields map[string]any
+
+// requestError indica uma requisição HTTP inválida (400).
+type requestError string
+
+func (e requestError) Error() string {
+	return string(e)
+}
+
+var (
+	errNoSession       = errors.New("sessão inexistente ou expirada; faça login em /api/login")
+	errTooManySessions = errors.New("limite de sessões atingido; encerre uma sessão em /api/logout")
+)
+
+type gateway struct {
+	sessions *sessions
+	// Origem liberada por CORS (vazio desativa)
+	allowOrigin string
+}
+
+// sessionHandler atende uma rota que exige login.
+type sessionHandler func(w http.ResponseWriter, r *http.Request, s *session)
+
+func (g *gateway) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/login", g.handleLogin)
+	mux.HandleFunc("/api/logout", g.withSession(g.handleLogout))
+	mux.HandleFunc("/api/users", g.withSession(g.handleUsers))
+	mux.HandleFunc("/api/channels", g.withSession(g.handleChannels))
+	mux.HandleFunc("/api/channels/", g.withSession(g.handleChannelMessages))
+	mux.HandleFunc("/api/messages", g.withSession(g.handleSendMessage))
+	mux.HandleFunc("/api/messages/", g.withSession(g.handleConversation))
+	mux.HandleFunc("/api/subscriptions", g.withSession(g.handleSubscriptions))
+	mux.HandleFunc("/api/subscriptions/", g.withSession(g.handleUnsubscribe))
+	mux.HandleFunc("/api/events", g.withStreamSession(g.handleEvents))
+	return g.cors(mux)
+}
+
+// cors libera as chamadas de um front end servido em outra origem.
+func (g *gateway) cors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if g.allowOrigin != "" {
+			w.Header().Set("Access-Control-Allow-Origin", g.allowOrigin)
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE")
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withSession encontra a sessão pelo token em "Authorization: Bearer".
+func (g *gateway) withSession(next sessionHandler) http.HandlerFunc {
+	return g.authorize(next, false)
+}
+
+// withStreamSession aceita também o token em ?token=, para o EventSource dos
+// navegadores, que não envia cabeçalhos. Só vale para o stream: em outras
+// rotas a URL com o token acabaria em logs e no histórico do navegador sem
+// necessidade.
+func (g *gateway) withStreamSession(next sessionHandler) http.HandlerFunc {
+	return g.authorize(next, true)
+}
+
+func (g *gateway) authorize(next sessionHandler, queryToken bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" && queryToken {
+			token = r.URL.Query().Get("token")
+		}
+		s, ok := g.sessions.get(token)
+		if token == "" || !ok {
+			writeError(w, errNoSession)
+			return
+		}
+		next(w, r, s)
+	}
+}
+
+// POST /api/login {"user"}
+func (g *gateway) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodPost) {
+		return
+	}
+	var body struct {
+		User string `json:"user"`
+	}
+	err := readJSON(r, &body)
+	if err == nil && strings.TrimSpace(body.User) == "" {
+		err = requestError(`informe {"user": <nome>}`)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	s, response, err := g.sessions.login(r.Context(), body.User)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeResult(w, http.StatusOK, response.Header, fields{"user": s.user, "token": s.token})
+}
+
+// POST /api/logout
+func (g *gateway) handleLogout(w http.ResponseWriter, r *http.Request, s *session) {
+	if !allow(w, r, http.MethodPost) {
+		return
+	}
+	g.sessions.logout(s.token)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /api/users
+func (g *gateway) handleUsers(w http.ResponseWriter, r *http.Request, s *session) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	response, err := s.client.ListUsers(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeResult(w, http.StatusOK, response.Header, fields{"users": response.Users})
+}
+
+// GET /api/channels lista os canais; POST /api/channels {"channel"} cria um.
+func (g *gateway) handleChannels(w http.ResponseWriter, r *http.Request, s *session) {
+	if !allow(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+	if r.Method == http.MethodGet {
+		response, err := s.client.ListChannels(r.Context())
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeResult(w, http.StatusOK, response.Header, fields{"channels": response.Channels})
+		return
+	}
+
+	var body struct {
+		Channel string `json:"channel"`
+	}
+	err := readJSON(r, &body)
+	if err == nil && body.Channel == "" {
+		err = requestError(`informe {"channel": <canal>}`)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	response, err := s.client.CreateChannel(r.Context(), body.Channel)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeResult(w, http.StatusCreated, response.Header, fields{"channel": body.Channel})
+}
+
+// GET /api/channels/{canal}/messages retorna o histórico;
+// POST /api/channels/{canal}/messages {"message", "meta"} publica.
+func (g *gateway) handleChannelMessages(w http.ResponseWriter, r *http.Request, s *session) {
+	channel, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/api/channels/"), "/messages")
+	if !ok || channel == "" || strings.Contains(channel, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	if !allow(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		q, err := historyQuery(r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		response, err := s.client.History(r.Context(), channel, q)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		publications := make([]fields, 0, len(response.Publications))
+		for _, p := range response.Publications {
+			publications = append(publications, publicationFields(p))
+		}
+		writeResult(w, http.StatusOK, response.Header, fields{
+			"channel":      channel,
+			"publications": publications,
+			"more":         response.More,
+		})
+		return
+	}
+
+	var body struct {
+		Message string       `json:"message"`
+		Meta    *requestMeta `json:"meta"`
+	}
+	err := readJSON(r, &body)
+	if err == nil && body.Message == "" {
+		err = requestError(`informe {"message": <texto>}`)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	response, err := s.client.PublishMessageWithMeta(r.Context(), channel, body.Message, body.Meta.meta())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeResult(w, http.StatusCreated, response.Header, fields{
+		"channel":   channel,
+		"duplicate": response.Duplicate,
+	})
+}
+
+// POST /api/messages {"to", "message", "meta"} envia uma mensagem privada.
+func (g *gateway) handleSendMessage(w http.ResponseWriter, r *http.Request, s *session) {
+	if !allow(w, r, http.MethodPost) {
+		return
+	}
+	var body struct {
+		To      string       `json:"to"`
+		Message string       `json:"message"`
+		Meta    *requestMeta `json:"meta"`
+	}
+	err := readJSON(r, &body)
+	if err == nil && (body.To == "" || body.Message == "") {
+		err = requestError(`informe {"to": <usuário>, "message": <texto>}`)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	response, err := s.client.SendPrivateMessageWithMeta(r.Context(), body.To, body.Message, body.Meta.meta())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeResult(w, http.StatusCreated, response.Header, fields{
+		"to":        body.To,
+		"duplicate": response.Duplicate,
+	})
+}
+
+// GET /api/messages/{usuário} retorna as mensagens privadas com o usuário.
+func (g *gateway) handleConversation(w http.ResponseWriter, r *http.Request, s *session) {
+	peer := strings.TrimPrefix(r.URL.Path, "/api/messages/")
+	if peer == "" || strings.Contains(peer, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	q, err := historyQuery(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	response, err := s.client.DMHistory(r.Context(), peer, q)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	messages := make([]fields, 0, len(response.Messages))
+	for _, m := range response.Messages {
+		messages = append(messages, privateMessageFields(m))
+	}
+	writeResult(w, http.StatusOK, response.Header, fields{
+		"peer":     peer,
+		"messages": messages,
+		"more":     response.More,
+	})
+}
+
+// GET /api/subscriptions lista as assinaturas da sessão;
+// POST /api/subscriptions {"channel"} assina um canal.
+func (g *gateway) handleSubscriptions(w http.ResponseWriter, r *http.Request, s *session) {
+	if !allow(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+	if r.Method == http.MethodPost {
+		var body struct {
+			Channel string `json:"channel"`
+		}
+		err := readJSON(r, &body)
+		if err == nil && body.Channel == "" {
+			err = requestError(`informe {"channel": <canal>}`)
+		}
+		if err == nil {
+			err = s.client.Subscribe(body.Channel)
+		}
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	writeResult(w, http.StatusOK, s.localHeader(), fields{"subscriptions": s.client.Subscriptions()})
+}
+
+// DELETE /api/subscriptions/{canal}
+func (g *gateway) handleUnsubscribe(w http.ResponseWriter, r *http.Request, s *session) {
+	channel := strings.TrimPrefix(r.URL.Path, "/api/subscriptions/")
+	if channel == "" || strings.Contains(channel, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	if !allow(w, r, http.MethodDelete) {
+		return
+	}
+	s.client.Unsubscribe(channel)
+	writeResult(w, http.StatusOK, s.localHeader(), fields{"subscriptions": s.client.Subscriptions()})
+}
+
+// GET /api/events abre um stream Server-Sent Events com as publicações dos
+// canais assinados, as mensagens privadas e as mudanças da conexão.
+func (g *gateway) handleEvents(w http.ResponseWriter, r *http.Request, s *session) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, errors.New("o servidor HTTP não suporta streaming"))
+		return
+	}
+	stream, ok := s.attach()
+	if !ok {
+		writeError(w, errNoSession)
+		return
+	}
+	defer s.detach(stream)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	writeEvent(w, event{"ready", fields{"user": s.user}})
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-stream:
+			if !ok {
+				// Sessão encerrada (logout ou expiração)
+				return
+			}
+			writeEvent(w, e)
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		}
+		flusher.Flush()
+	}
+}
+
+// localHeader é o cabeçalho das respostas que não passam pelo servidor.
+func (s *session) localHeader() chatsdk.Header {
+	return chatsdk.Header{Timestamp: time.Now().UnixMilli(), Clock: s.client.Clock()}
+}
+
+// writeEvent escreve um evento SSE; o JSON fica em uma única linha de data.
+func writeEvent(w io.Writer, e event) {
+	fmt.Fprintf(w, "event: %s\ndata: ", e.name)
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(e.data)
+	fmt.Fprint(w, "\n")
+}
+
+// allow responde 405 se o método não estiver entre methods.
+func allow(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, method := range methods {
+		if r.Method == method {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeJSON(w, http.StatusMethodNotAllowed, fields{"error": "método não permitido"})
+	return false
+}
+
+func readJSON(r *http.Request, v any) error {
+	err := json.NewDecoder(io.LimitReader(r.Body, maxBody)).Decode(v)
+	if err != nil {
+		return requestError(fmt.Sprintf("corpo JSON inválido: %v", err))
+	}
+	return nil
+}
+
+// requestMeta é o meta aceito no corpo de publish e message: só o id. vc e
+// hlc enviados pela rede são ignorados; o SDK da sessão preenche os seus.
+type requestMeta struct {
+	ID string `json:"id"`
+}
+
+func (m *requestMeta) meta() *chatsdk.Meta {
+	if m == nil || m.ID == "" {
+		return nil
+	}
+	return &chatsdk.Meta{ID: m.ID}
+}
+
+// historyQuery lê limit, since, before e before_id da query string.
+func historyQuery(r *http.Request) (chatsdk.HistoryQuery, error) {
+	values := r.URL.Query()
+	number := func(name string) (int64, error) {
+		value := values.Get(name)
+		if value == "" {
+			return 0, nil
+		}
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < 0 {
+			return 0, requestError(fmt.Sprintf("%s inválido: %q", name, value))
+		}
+		return n, nil
+	}
+
+	var q chatsdk.HistoryQuery
+	limit, err := number("limit")
+	if err != nil {
+		return q, err
+	}
+	q.Limit = int(limit)
+	if q.Since, err = number("since"); err != nil {
+		return q, err
+	}
+	if q.Before, err = number("before"); err != nil {
+		return q, err
+	}
+	q.BeforeID = values.Get("before_id")
+	return q, nil
+}
+
+// writeResult escreve o resultado com o timestamp e o clock da resposta.
+func writeResult(w http.ResponseWriter, status int, header chatsdk.Header, result fields) {
+	result["timestamp"] = header.Timestamp
+	result["clock"] = header.Clock
+	writeJSON(w, status, result)
+}
+
+// writeError escreve {"error", "code"} com o status HTTP correspondente: 400
+// para requisições inválidas, 401 sem sessão, 422 se o servidor recusou o
+// pedido, 504 se nenhuma réplica respondeu e 502 nos demais casos.
+func writeError(w http.ResponseWriter, err error) {
+	var invalid requestError
+	var serverErr *chatsdk.ServerError
+	status, code := http.StatusBadGateway, "error"
+	switch {
+	case errors.As(err, &invalid):
+		status, code = http.StatusBadRequest, "usage"
+	case errors.Is(err, errNoSession):
+		status, code = http.StatusUnauthorized, "session"
+	case errors.Is(err, errTooManySessions):
+		status, code = http.StatusTooManyRequests, "sessions"
+	case errors.As(err, &serverErr):
+		status, code = http.StatusUnprocessableEntity, "server"
+	case errors.Is(err, chatsdk.ErrTimeout):
+		status, code = http.StatusGatewayTimeout, "timeout"
+	}
+	writeJSON(w, status, fields{"error": err.Error(), "code": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(v)
+}
+
+func publicationFields(p chatsdk.Publication) fields {
+	data := fields{
+		"channel":   p.Channel,
+		"user":      p.User,
+		"message":   p.Message,
+		"timestamp": p.Timestamp,
+		"clock":     p.Clock,
+	}
+	if p.RequestID != "" {
+		data["request_id"] = p.RequestID
+	}
+	if p.Meta != nil {
+		data["meta"] = p.Meta
+	}
+	return data
+}
+
+func privateMessageFields(m chatsdk.PrivateMessage) fields {
+	data := fields{
+		"src":       m.Src,
+		"dst":       m.Dst,
+		"message":   m.Message,
+		"timestamp": m.Timestamp,
+		"clock":     m.Clock,
+	}
+	if m.RequestID != "" {
+		data["request_id"] = m.RequestID
+	}
+	if m.Meta != nil {
+		data["meta"] = m.Meta
+	}
+	return data
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"chat-client/internal/config"
+)
+
+const (
+	defaultListen     = "127.0.0.1:8080"
+	defaultSessionTTL = 30 * time.Minute
+	// Cada sessão mantém dois sockets ZMQ abertos
+	defaultMaxSessions        = 100
+	defaultMaxSessionsPerUser = 5
+	// Espera pelas requisições em andamento ao encerrar
+	shutdownTimeout = 5 * time.Second
+)
+
+// O gateway expõe o chat em HTTP para navegadores e ferramentas que não falam
+// ZMQ: endpoints REST para login, usuários, canais, publicações e mensagens
+// privadas e um stream Server-Sent Events com o que chega pela assinatura.
+// Cada login abre um chatsdk.Client próprio.
+func main() {
+	listen := flag.String("listen", defaultListen, "endereço HTTP do gateway")
+	sessionTTL := flag.Duration("session-ttl", defaultSessionTTL, "encerra sessões sem requisições nem streams abertos por esse tempo")
+	allowOrigin := flag.String("allow-origin", "", "origem liberada por CORS (* para qualquer), vazio desativa")
+	maxSessions := flag.Int("max-sessions", defaultMaxSessions, "máximo de sessões abertas")
+	maxPerUser := flag.Int("max-sessions-per-user", defaultMaxSessionsPerUser, "máximo de sessões abertas por usuário")
+
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Fatal("Erro na configuração:", err)
+	}
+	if *sessionTTL <= 0 {
+		log.Fatal("Erro na configuração: --session-ttl deve ser positivo")
+	}
+	if *maxSessions <= 0 || *maxPerUser <= 0 {
+		log.Fatal("Erro na configuração: --max-sessions e --max-sessions-per-user devem ser positivos")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	registry := newSessions(cfg, *sessionTTL, *maxSessions, *maxPerUser)
+	go registry.expire(ctx)
+
+	g := &gateway{sessions: registry, allowOrigin: *allowOrigin}
+	server := &http.Server{
+		Addr:              *listen,
+		Handler:           g.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		// Fechar as sessões termina os streams, que senão prenderiam o
+		// Shutdown
+		registry.closeAll()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("Gateway HTTP em %s (broker %s, proxy %s)", *listen, cfg.Broker, cfg.Proxy)
+	err = server.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal("Erro no servidor HTTP:", err)
+	}
+	<-stopped
+}
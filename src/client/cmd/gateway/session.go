@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"chat-client/chatsdk"
+	"chat-client/internal/config"
+)
+
+// Eventos guardados por stream enquanto o navegador não os lê; além disso
+// são descartados
+const streamBuffer = 64
+
+// event é uma mensagem enviada aos streams de /api/events.
+type event struct {
+	name string
+	data fields
+}
+
+// session é um usuário logado pelo gateway, com seu próprio chatsdk.Client
+// (sockets REQ e SUB). O que chega pela assinatura vai para os streams
+// abertos pela sessão.
+type session struct {
+	token  string
+	user   string
+	client *chatsdk.Client
+
+	mu       sync.Mutex
+	streams  map[chan event]struct{}
+	lastUsed time.Time
+	closed   bool
+}
+
+// attach abre um stream de eventos. Retorna false se a sessão já terminou.
+func (s *session) attach() (chan event, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, false
+	}
+	stream := make(chan event, streamBuffer)
+	s.streams[stream] = struct{}{}
+	return stream, true
+}
+
+func (s *session) detach(stream chan event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.streams[stream]; ok {
+		delete(s.streams, stream)
+		close(stream)
+	}
+	s.lastUsed = time.Now()
+}
+
+// broadcast entrega e a todos os streams abertos, sem esperar pelos lentos.
+func (s *session) broadcast(e event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for stream := range s.streams {
+		select {
+		case stream <- e:
+		default:
+			log.Printf("Stream de '%s' cheio; evento %s descartado", s.user, e.name)
+		}
+	}
+}
+
+func (s *session) touch() {
+	s.mu.Lock()
+	s.lastUsed = time.Now()
+	s.mu.Unlock()
+}
+
+// idle indica se a sessão está sem streams e sem requisições há mais de ttl.
+func (s *session) idle(now time.Time, ttl time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.streams) == 0 && now.Sub(s.lastUsed) > ttl
+}
+
+// close encerra os streams e o cliente da sessão.
+func (s *session) close() {
+	s.mu.Lock()
+	s.closed = true
+	for stream := range s.streams {
+		delete(s.streams, stream)
+		close(stream)
+	}
+	s.mu.Unlock()
+
+	s.client.Close()
+}
+
+// sessions guarda as sessões abertas pelo token.
+type sessions struct {
+	cfg *config.Config
+	ttl time.Duration
+	// Máximo de sessões abertas, no total e por usuário
+	maxTotal   int
+	maxPerUser int
+
+	mu      sync.Mutex
+	byToken map[string]*session
+	// Sessões abertas ou em login, no total e por usuário
+	total  int
+	byUser map[string]int
+}
+
+func newSessions(cfg *config.Config, ttl time.Duration, maxTotal, maxPerUser int) *sessions {
+	return &sessions{
+		cfg:        cfg,
+		ttl:        ttl,
+		maxTotal:   maxTotal,
+		maxPerUser: maxPerUser,
+		byToken:    make(map[string]*session),
+		byUser:     make(map[string]int),
+	}
+}
+
+// reserve conta uma sessão de user antes do login, que abre sockets, ou
+// retorna errTooManySessions se um dos limites foi atingido.
+func (r *sessions) reserve(user string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.total >= r.maxTotal || r.byUser[user] >= r.maxPerUser {
+		return errTooManySessions
+	}
+	r.total++
+	r.byUser[user]++
+	return nil
+}
+
+// release descarta a contagem de uma sessão de user.
+func (r *sessions) release(user string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.total--
+	r.byUser[user]--
+	if r.byUser[user] <= 0 {
+		delete(r.byUser, user)
+	}
+}
+
+// login conecta um novo cliente, faz o login e registra a sessão.
+func (r *sessions) login(ctx context.Context, user string) (*session, *chatsdk.LoginResponse, error) {
+	err := r.reserve(user)
+	if err != nil {
+		return nil, nil, err
+	}
+	s, response, err := r.connect(ctx, user)
+	if err != nil {
+		r.release(user)
+		return nil, nil, err
+	}
+	return s, response, nil
+}
+
+// connect abre o cliente da sessão e faz o login.
+func (r *sessions) connect(ctx context.Context, user string) (*session, *chatsdk.LoginResponse, error) {
+	client, err := chatsdk.New(r.cfg.SDKOptions()...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("erro ao criar cliente: %v", err)
+	}
+	err = client.Connect()
+	if err != nil {
+		client.Close()
+		return nil, nil, fmt.Errorf("erro ao conectar: %w", err)
+	}
+	response, err := client.Login(ctx, user)
+	if err != nil {
+		client.Close()
+		return nil, nil, err
+	}
+
+	token, err := newToken()
+	if err != nil {
+		client.Close()
+		return nil, nil, err
+	}
+	s := &session{
+		token:    token,
+		user:     user,
+		client:   client,
+		streams:  make(map[chan event]struct{}),
+		lastUsed: time.Now(),
+	}
+	client.Listen(chatsdk.Handler{
+		Publication: func(p chatsdk.Publication) {
+			s.broadcast(event{chatsdk.ServicePublication, publicationFields(p)})
+		},
+		PrivateMessage: func(m chatsdk.PrivateMessage) {
+			s.broadcast(event{chatsdk.ServicePrivateMessage, privateMessageFields(m)})
+		},
+		State: func(e chatsdk.ConnectionEvent) {
+			data := fields{"state": e.State.String(), "recovered": e.Recovered}
+			if e.Err != nil {
+				data["error"] = e.Err.Error()
+			}
+			s.broadcast(event{"connection", data})
+		},
+	})
+
+	r.mu.Lock()
+	r.byToken[token] = s
+	r.mu.Unlock()
+	log.Printf("Sessão aberta para '%s'", user)
+	return s, response, nil
+}
+
+// get retorna a sessão do token, marcando-a como usada.
+func (r *sessions) get(token string) (*session, bool) {
+	r.mu.Lock()
+	s, ok := r.byToken[token]
+	r.mu.Unlock()
+	if ok {
+		s.touch()
+	}
+	return s, ok
+}
+
+// logout encerra a sessão do token.
+func (r *sessions) logout(token string) {
+	r.mu.Lock()
+	s, ok := r.byToken[token]
+	delete(r.byToken, token)
+	r.mu.Unlock()
+	if ok {
+		s.close()
+		r.release(s.user)
+		log.Printf("Sessão de '%s' encerrada", s.user)
+	}
+}
+
+// expire encerra periodicamente as sessões paradas há mais de ttl, até que
+// ctx termine.
+func (r *sessions) expire(ctx context.Context) {
+	ticker := time.NewTicker(r.ttl / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			r.mu.Lock()
+			var expired []string
+			for token, s := range r.byToken {
+				if s.idle(now, r.ttl) {
+					expired = append(expired, token)
+				}
+			}
+			r.mu.Unlock()
+			for _, token := range expired {
+				r.logout(token)
+			}
+		}
+	}
+}
+
+// closeAll encerra todas as sessões.
+func (r *sessions) closeAll() {
+	r.mu.Lock()
+	tokens := make([]string, 0, len(r.byToken))
+	for token := range r.byToken {
+		tokens = append(tokens, token)
+	}
+	r.mu.Unlock()
+	for _, token := range tokens {
+		r.logout(token)
+	}
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", fmt.Errorf("erro ao gerar token: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,438 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"chat-client/chatsdk"
+	"chat-client/internal/config"
+)
+
+// Tamanho máximo de uma linha recebida (512 no RFC, mais as tags do IRCv3)
+const maxLine = 8192
+
+// ircConn é a conexão de um cliente IRC. Depois de NICK e USER, ela ganha um
+// chatsdk.Client próprio, com o login feito com o nick.
+type ircConn struct {
+	cfg    *config.Config
+	limits *sessionLimits
+	conn   net.Conn
+	ctx    context.Context
+
+	// A goroutine de Listen e a de comandos escrevem na conexão
+	mu sync.Mutex
+	w  *bufio.Writer
+
+	nick   string
+	user   bool
+	client *chatsdk.Client
+	// Canais em que o cliente IRC entrou (JOIN), sem "#"
+	joined map[string]bool
+}
+
+// serve atende a conexão até QUIT ou até ela ser fechada.
+func serve(ctx context.Context, cfg *config.Config, limits *sessionLimits, conn net.Conn) {
+	c := &ircConn{
+		cfg:    cfg,
+		limits: limits,
+		conn:   conn,
+		ctx:    ctx,
+		w:      bufio.NewWriter(conn),
+		joined: make(map[string]bool),
+	}
+	defer c.close()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 1024), maxLine)
+	for scanner.Scan() {
+		m, ok := parseMessage(strings.TrimRight(scanner.Text(), "\r"))
+		if !ok {
+			continue
+		}
+		if !c.handle(m) {
+			return
+		}
+	}
+}
+
+func (c *ircConn) close() {
+	if c.client != nil {
+		c.client.Close()
+		c.limits.release(c.nick)
+		log.Printf("Conexão IRC de '%s' encerrada", c.nick)
+	}
+	c.conn.Close()
+}
+
+// send escreve uma mensagem na conexão.
+func (c *ircConn) send(prefix, command string, params ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.w.WriteString(formatMessage(prefix, command, params...) + "\r\n")
+	c.w.Flush()
+}
+
+// reply envia uma resposta numérica do servidor, endereçada ao nick.
+func (c *ircConn) reply(code string, params ...string) {
+	target := c.nick
+	if target == "" {
+		target = "*"
+	}
+	c.send(serverName, code, append([]string{target}, params...)...)
+}
+
+// notice envia um aviso do gateway ao cliente.
+func (c *ircConn) notice(text string) {
+	target := c.nick
+	if target == "" {
+		target = "*"
+	}
+	c.send(serverName, "NOTICE", target, text)
+}
+
+// handle executa um comando e retorna false se a conexão deve ser fechada.
+func (c *ircConn) handle(m ircMessage) bool {
+	switch m.command {
+	case "QUIT":
+		c.send("", "ERROR", "Encerrando a conexão")
+		return false
+	case "PING":
+		c.send(serverName, "PONG", serverName, m.param(0))
+		return true
+	case "PONG", "PASS":
+		return true
+	case "CAP":
+		// Sem capacidades do IRCv3; o cliente segue com o registro normal
+		if strings.ToUpper(m.param(0)) == "LS" {
+			c.send(serverName, "CAP", "*", "LS", "")
+		}
+		return true
+	case "NICK":
+		return c.setNick(m)
+	case "USER":
+		if c.client != nil {
+			c.reply(errRegistered, "Você já está registrado")
+			return true
+		}
+		if len(m.params) < 4 {
+			c.reply(errNeedMoreParam, "USER", "Parâmetros insuficientes")
+			return true
+		}
+		c.user = true
+		return c.register()
+	}
+
+	if c.client == nil {
+		c.reply(errNotRegistered, "Você não está registrado")
+		return true
+	}
+
+	switch m.command {
+	case "JOIN":
+		c.join(m)
+	case "PART":
+		c.part(m)
+	case "PRIVMSG", "NOTICE":
+		c.privmsg(m)
+	case "LIST":
+		c.list()
+	case "NAMES":
+		c.names(m)
+	case "WHO":
+		c.reply(rplEndOfWho, m.param(0), "Fim do WHO")
+	case "MODE":
+		if _, ok := chatChannel(m.param(0)); ok {
+			c.reply(rplChannelModeIs, m.param(0), "+")
+		} else {
+			c.reply(rplUModeIs, "+")
+		}
+	case "TOPIC":
+		c.reply(rplNoTopic, m.param(0), "Canais do chat não têm tópico")
+	default:
+		c.reply(errUnknownCmd, m.command, "Comando desconhecido")
+	}
+	return true
+}
+
+// setNick guarda o nick até o registro. O nick é o usuário do chat, então não
+// pode ser trocado depois do login.
+func (c *ircConn) setNick(m ircMessage) bool {
+	nick := m.param(0)
+	if nick == "" {
+		c.reply(errNoNickname, "Nenhum nick informado")
+		return true
+	}
+	if c.client != nil {
+		if nick != c.nick {
+			c.reply(errBadNickname, ircName(nick, badNickChars), "O nick é o usuário do chat e não pode ser trocado; reconecte com o novo nick")
+		}
+		return true
+	}
+	if !validNick(nick) {
+		c.reply(errBadNickname, ircName(nick, badNickChars), "Nick inválido")
+		return true
+	}
+	c.nick = nick
+	return c.register()
+}
+
+// register faz o login quando NICK e USER já chegaram.
+func (c *ircConn) register() bool {
+	if c.nick == "" || !c.user || c.client != nil {
+		return true
+	}
+
+	if !c.limits.reserve(c.nick) {
+		c.send("", "ERROR", "Limite de conexões do gateway atingido")
+		return false
+	}
+	client, err := chatsdk.New(c.cfg.SDKOptions()...)
+	if err == nil {
+		err = client.Connect()
+		if err == nil {
+			_, err = client.Login(c.ctx, c.nick)
+		}
+		if err != nil {
+			client.Close()
+		}
+	}
+	if err != nil {
+		c.limits.release(c.nick)
+	}
+	var serverErr *chatsdk.ServerError
+	if errors.As(err, &serverErr) {
+		// O cliente IRC pode tentar outro nick
+		c.reply(errBadNickname, c.nick, serverErr.Description)
+		c.nick = ""
+		return true
+	}
+	if err != nil {
+		c.send("", "ERROR", fmt.Sprintf("Erro ao fazer login no chat: %v", err))
+		return false
+	}
+
+	c.client = client
+	client.Listen(chatsdk.Handler{
+		Publication:    c.relayPublication,
+		PrivateMessage: c.relayPrivateMessage,
+		State:          c.relayState,
+	})
+	log.Printf("Conexão IRC de '%s' registrada", c.nick)
+
+	c.reply(rplWelcome, fmt.Sprintf("Bem-vindo ao chat, %s", userPrefix(c.nick)))
+	c.reply(rplYourHost, fmt.Sprintf("Servidor %s, ponte para o broker %s", serverName, c.cfg.Broker))
+	c.reply(rplCreated, fmt.Sprintf("Gateway iniciado em %s", started.Format(time.RFC1123)))
+	c.reply(rplMyInfo, serverName, "1.0", "i", "n")
+	c.reply(errNoMotd, "Sem MOTD; use LIST e JOIN #canal")
+	return true
+}
+
+// join cria os canais que ainda não existem e os assina. JOIN 0 sai de todos.
+func (c *ircConn) join(m ircMessage) {
+	if m.param(0) == "" {
+		c.reply(errNeedMoreParam, "JOIN", "Parâmetros insuficientes")
+		return
+	}
+	if m.param(0) == "0" {
+		for channel := range c.joined {
+			c.leave(channel, "")
+		}
+		return
+	}
+
+	var existing []string
+	listed := false
+	for _, name := range strings.Split(m.param(0), ",") {
+		channel, ok := chatChannel(name)
+		if !ok {
+			c.reply(errNoSuchChannel, name, "Os canais começam com #")
+			continue
+		}
+		if c.joined[channel] {
+			continue
+		}
+
+		// A lista é buscada uma vez por JOIN, só se for preciso
+		if !listed {
+			response, err := c.client.ListChannels(c.ctx)
+			if err != nil {
+				c.notice(fmt.Sprintf("Erro ao entrar em %s: %v", name, err))
+				return
+			}
+			existing, listed = response.Channels, true
+		}
+		if !slices.Contains(existing, channel) {
+			_, err := c.client.CreateChannel(c.ctx, channel)
+			if err != nil {
+				c.reply(errNoSuchChannel, name, err.Error())
+				continue
+			}
+			existing = append(existing, channel)
+		}
+
+		err := c.client.Subscribe(channel)
+		if err != nil {
+			c.notice(fmt.Sprintf("Erro ao assinar %s: %v", name, err))
+			continue
+		}
+		c.joined[channel] = true
+		c.send(userPrefix(c.nick), "JOIN", name)
+		c.reply(rplNoTopic, name, "Canais do chat não têm tópico")
+		c.sendNames(name)
+	}
+}
+
+// part cancela a assinatura dos canais.
+func (c *ircConn) part(m ircMessage) {
+	if m.param(0) == "" {
+		c.reply(errNeedMoreParam, "PART", "Parâmetros insuficientes")
+		return
+	}
+	for _, name := range strings.Split(m.param(0), ",") {
+		channel, ok := chatChannel(name)
+		if !ok || !c.joined[channel] {
+			c.reply(errNoSuchChannel, name, "Você não está nesse canal")
+			continue
+		}
+		c.leave(channel, m.param(1))
+	}
+}
+
+func (c *ircConn) leave(channel, reason string) {
+	c.client.Unsubscribe(channel)
+	delete(c.joined, channel)
+	params := []string{ircChannel(channel)}
+	if reason != "" {
+		params = append(params, reason)
+	}
+	c.send(userPrefix(c.nick), "PART", params...)
+}
+
+// privmsg publica em "#canal" ou envia mensagem privada para "nick". NOTICE
+// segue o mesmo caminho, mas sem respostas de erro, como pede o RFC.
+func (c *ircConn) privmsg(m ircMessage) {
+	quiet := m.command == "NOTICE"
+	if len(m.params) < 2 || m.param(1) == "" {
+		if !quiet {
+			c.reply(errNeedMoreParam, m.command, "Parâmetros insuficientes")
+		}
+		return
+	}
+	text := m.param(1)
+
+	for _, target := range strings.Split(m.param(0), ",") {
+		var err error
+		code := errNoSuchNick
+		if channel, ok := chatChannel(target); ok {
+			code = errCannotSend
+			_, err = c.client.PublishMessage(c.ctx, channel, text)
+		} else {
+			_, err = c.client.SendPrivateMessage(c.ctx, target, text)
+		}
+		if err == nil || quiet {
+			continue
+		}
+
+		var serverErr *chatsdk.ServerError
+		if errors.As(err, &serverErr) {
+			c.reply(code, target, serverErr.Description)
+		} else {
+			c.notice(fmt.Sprintf("Mensagem para %s não enviada: %v", target, err))
+		}
+	}
+}
+
+// list responde LIST com os canais do chat.
+func (c *ircConn) list() {
+	response, err := c.client.ListChannels(c.ctx)
+	if err != nil {
+		c.notice(fmt.Sprintf("Erro ao listar canais: %v", err))
+		return
+	}
+	c.reply(rplListStart, "Channel", "Users Name")
+	for _, channel := range response.Channels {
+		// Canais com nomes que o IRC não representa não podem ser usados
+		if _, ok := chatChannel("#" + channel); ok {
+			c.reply(rplList, ircChannel(channel), "0", "")
+		}
+	}
+	c.reply(rplListEnd, "Fim do LIST")
+}
+
+// names responde NAMES para os canais pedidos ou, sem parâmetro, para os
+// canais em que o cliente entrou.
+func (c *ircConn) names(m ircMessage) {
+	var names []string
+	if m.param(0) != "" {
+		names = strings.Split(m.param(0), ",")
+	} else {
+		for channel := range c.joined {
+			names = append(names, ircChannel(channel))
+		}
+		sort.Strings(names)
+	}
+	for _, name := range names {
+		c.sendNames(name)
+	}
+}
+
+// sendNames lista os usuários do chat. O servidor não guarda quem assina cada
+// canal, então todo canal mostra todos os usuários.
+func (c *ircConn) sendNames(name string) {
+	response, err := c.client.ListUsers(c.ctx)
+	if err != nil {
+		c.notice(fmt.Sprintf("Erro ao listar usuários: %v", err))
+	} else {
+		// Em lotes, para não passar do tamanho de linha do IRC
+		users := make([]string, len(response.Users))
+		for i, user := range response.Users {
+			users[i] = ircName(user, badNickChars)
+		}
+		for len(users) > 0 {
+			n := min(len(users), 30)
+			c.reply(rplNamReply, "=", name, strings.Join(users[:n], " "))
+			users = users[n:]
+		}
+	}
+	c.reply(rplEndOfNames, name, "Fim do NAMES")
+}
+
+// relayPublication repassa uma publicação como PRIVMSG no canal. As do
+// próprio nick são omitidas: o cliente IRC já exibe o que enviou.
+func (c *ircConn) relayPublication(p chatsdk.Publication) {
+	if p.User == c.nick {
+		return
+	}
+	for _, line := range textLines(p.Message) {
+		c.send(userPrefix(p.User), "PRIVMSG", ircChannel(p.Channel), line)
+	}
+}
+
+// relayPrivateMessage repassa uma mensagem privada como PRIVMSG para o nick.
+func (c *ircConn) relayPrivateMessage(m chatsdk.PrivateMessage) {
+	for _, line := range textLines(m.Message) {
+		c.send(userPrefix(m.Src), "PRIVMSG", c.nick, line)
+	}
+}
+
+// relayState avisa quando a assinatura cai e quando volta.
+func (c *ircConn) relayState(e chatsdk.ConnectionEvent) {
+	switch {
+	case e.State == chatsdk.StateDisconnected:
+		c.notice(fmt.Sprintf("Conexão com o proxy perdida: %v", e.Err))
+	case e.State == chatsdk.StateConnected && !e.Since.IsZero():
+		c.notice(fmt.Sprintf("Conexão com o proxy restabelecida após %v; %d mensagens recuperadas",
+			time.Since(e.Since).Round(time.Second), e.Recovered))
+	case e.State == chatsdk.StateConnected && (e.Recovered > 0 || e.Err != nil):
+		c.notice(fmt.Sprintf("%d mensagens perdidas recuperadas do histórico", e.Recovered))
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"strings"
+)
+
+// Respostas numéricas usadas (RFC 1459 e RFC 2812)
+const (
+	rplWelcome       = "001"
+	rplYourHost      = "002"
+	rplCreated       = "003"
+	rplMyInfo        = "004"
+	rplUModeIs       = "221"
+	rplEndOfWho      = "315"
+	rplListStart     = "321"
+	rplList          = "322"
+	rplListEnd       = "323"
+	rplChannelModeIs = "324"
+	rplNoTopic       = "331"
+	rplNamReply      = "353"
+	rplEndOfNames    = "366"
+	errNoSuchNick    = "401"
+	errNoSuchChannel = "403"
+	errCannotSend    = "404"
+	errUnknownCmd    = "421"
+	errNoMotd        = "422"
+	errNoNickname    = "431"
+	errBadNickname   = "432"
+	errNotRegistered = "451"
+	errNeedMoreParam = "461"
+	errRegistered    = "462"
+)
+
+// ircMessage é uma linha do protocolo IRC: [:prefixo] comando [parâmetros]
+// [:último parâmetro, que pode ter espaços].
+type ircMessage struct {
+	prefix  string
+	command string
+	params  []string
+}
+
+// parseMessage interpreta uma linha recebida, já sem o "\r\n". Tags do
+// IRCv3 (@...) são ignoradas.
+func parseMessage(line string) (ircMessage, bool) {
+	var m ircMessage
+	if strings.HasPrefix(line, "@") {
+		_, line, _ = strings.Cut(line, " ")
+	}
+	if strings.HasPrefix(line, ":") {
+		m.prefix, line, _ = strings.Cut(line[1:], " ")
+	}
+
+	line, trailing, hasTrailing := strings.Cut(line, " :")
+	if strings.HasPrefix(line, ":") {
+		// Só o parâmetro final, logo depois do comando
+		trailing, hasTrailing, line = line[1:], true, ""
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return m, false
+	}
+	m.command = strings.ToUpper(fields[0])
+	m.params = fields[1:]
+	if hasTrailing {
+		m.params = append(m.params, trailing)
+	}
+	return m, true
+}
+
+// param retorna o i-ésimo parâmetro ou "" se ele não existir.
+func (m ircMessage) param(i int) string {
+	if i < len(m.params) {
+		return m.params[i]
+	}
+	return ""
+}
+
+// Comandos cujo último parâmetro é sempre texto livre e vai depois de ":",
+// como fazem os servidores IRC comuns
+var textCommands = map[string]bool{
+	"PRIVMSG": true,
+	"NOTICE":  true,
+	"PART":    true,
+	"PONG":    true,
+	"ERROR":   true,
+}
+
+// Caracteres que encerram ou cortam uma linha IRC; nunca vão para a conexão
+var lineBreaker = strings.NewReplacer("\r", " ", "\n", " ", "\x00", "")
+
+// formatMessage monta a linha de uma mensagem, sem o "\r\n". O último
+// parâmetro vai depois de ":" quando precisa (vazio, com espaço ou iniciado
+// por ":") ou quando é o texto de um dos textCommands. CR, LF e NUL que
+// sobrarem nos parâmetros são removidos, para que nada injete outra linha.
+func formatMessage(prefix, command string, params ...string) string {
+	text := textCommands[command] && (len(params) > 1 || command == "ERROR")
+	var b strings.Builder
+	if prefix != "" {
+		b.WriteString(":" + lineBreaker.Replace(prefix) + " ")
+	}
+	b.WriteString(command)
+	for i, param := range params {
+		param = lineBreaker.Replace(param)
+		b.WriteString(" ")
+		last := i == len(params)-1
+		if last && (text || param == "" || strings.Contains(param, " ") || strings.HasPrefix(param, ":")) {
+			b.WriteString(":")
+		}
+		b.WriteString(param)
+	}
+	return b.String()
+}
+
+// Caracteres que não podem aparecer em nicks e nomes de canais: separam
+// parâmetros, listas ou as partes do prefixo, ou encerram a linha
+const (
+	badChannelChars = " ,\r\n\x00\a"
+	badNickChars    = badChannelChars + "#:!@"
+)
+
+// validNick indica se o nick pode ir para uma linha IRC sem escape.
+func validNick(nick string) bool {
+	return nick != "" && !strings.ContainsAny(nick, badNickChars)
+}
+
+// ircName troca os caracteres inválidos de um nome vindo do chat por "_",
+// para que ele caiba numa linha IRC.
+func ircName(name, bad string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(bad, r) {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// Os canais do chat aparecem no IRC com "#"
+func ircChannel(channel string) string {
+	return "#" + ircName(channel, badChannelChars)
+}
+
+// chatChannel retorna o canal do chat de um nome IRC, ou false se o nome não
+// começar com "#" ou tiver caracteres inválidos.
+func chatChannel(name string) (string, bool) {
+	channel, ok := strings.CutPrefix(name, "#")
+	return channel, ok && channel != "" && !strings.ContainsAny(channel, badChannelChars)
+}
+
+// userPrefix é a origem das mensagens de um usuário do chat.
+func userPrefix(user string) string {
+	nick := ircName(user, badNickChars)
+	return nick + "!" + nick + "@" + serverName
+}
+
+// textLines divide o texto de uma mensagem do chat nas linhas IRC que a
+// representam, quebrando em CR, LF ou CRLF e removendo NUL.
+func textLines(text string) []string {
+	text = strings.ReplaceAll(text, "\x00", "")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	return strings.Split(text, "\n")
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"chat-client/internal/config"
+)
+
+const (
+	defaultListen = "127.0.0.1:6667"
+	// Cada conexão registrada mantém dois sockets ZMQ abertos
+	defaultMaxSessions        = 100
+	defaultMaxSessionsPerNick = 5
+)
+
+// Nome do servidor nas respostas e nos prefixos dos usuários
+const serverName = "chat-gateway"
+
+var started = time.Now()
+
+// sessionLimits conta as conexões registradas, no total e por nick, para
+// recusar logins além de --max-sessions e --max-sessions-per-nick.
+type sessionLimits struct {
+	maxTotal   int
+	maxPerNick int
+
+	mu     sync.Mutex
+	total  int
+	byNick map[string]int
+}
+
+// reserve conta uma sessão de nick antes do login, que abre sockets, ou
+// retorna false se um dos limites foi atingido.
+func (l *sessionLimits) reserve(nick string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.total >= l.maxTotal || l.byNick[nick] >= l.maxPerNick {
+		return false
+	}
+	l.total++
+	l.byNick[nick]++
+	return true
+}
+
+// release descarta a contagem de uma sessão de nick.
+func (l *sessionLimits) release(nick string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.total--
+	l.byNick[nick]--
+	if l.byNick[nick] <= 0 {
+		delete(l.byNick, nick)
+	}
+}
+
+// O gateway IRC aceita clientes IRC e traduz seus comandos para o protocolo
+// do chat: NICK faz o login, JOIN cria e assina o canal, PRIVMSG publica ou
+// envia mensagem privada e LIST/NAMES listam canais e usuários. O que chega
+// pela assinatura volta como PRIVMSG. Cada conexão abre um chatsdk.Client
+// próprio.
+func main() {
+	listen := flag.String("listen", defaultListen, "endereço TCP em que os clientes IRC se conectam")
+	maxSessions := flag.Int("max-sessions", defaultMaxSessions, "máximo de conexões registradas")
+	maxPerNick := flag.Int("max-sessions-per-nick", defaultMaxSessionsPerNick, "máximo de conexões registradas com o mesmo nick")
+
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Fatal("Erro na configuração:", err)
+	}
+	if *maxSessions <= 0 || *maxPerNick <= 0 {
+		log.Fatal("Erro na configuração: --max-sessions e --max-sessions-per-nick devem ser positivos")
+	}
+	limits := &sessionLimits{maxTotal: *maxSessions, maxPerNick: *maxPerNick, byNick: make(map[string]int)}
+
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		log.Fatal("Erro ao abrir porta IRC:", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Ao encerrar, fechar a porta e as conexões abertas
+	var mu sync.Mutex
+	conns := make(map[net.Conn]struct{})
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+		mu.Lock()
+		for conn := range conns {
+			conn.Close()
+		}
+		mu.Unlock()
+	}()
+
+	log.Printf("Gateway IRC em %s (broker %s, proxy %s)", *listen, cfg.Broker, cfg.Proxy)
+	var wg sync.WaitGroup
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Erro ao aceitar conexão: %v", err)
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			break
+		}
+
+		mu.Lock()
+		conns[conn] = struct{}{}
+		mu.Unlock()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			serve(ctx, cfg, limits, conn)
+			mu.Lock()
+			delete(conns, conn)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,343 @@
+// Package config monta a configuração dos binários do cliente a partir de,
+// em ordem crescente de prioridade: valores padrão, arquivo YAML, variáveis
+// de ambiente e flags de linha de comando.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"chat-client/chatsdk"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Variáveis de ambiente reconhecidas
+const (
+	EnvConfig   = "CHAT_CONFIG"
+	EnvBroker   = "CHAT_BROKER"
+	EnvProxy    = "CHAT_PROXY"
+	EnvUsername = "CHAT_USERNAME"
+	EnvTimeout  = "CHAT_TIMEOUT"
+	EnvRetries  = "CHAT_RETRIES"
+	EnvIdle     = "CHAT_IDLE_TIMEOUT"
+	EnvStoreDir = "CHAT_STORE_DIR"
+	EnvCausal   = "CHAT_CAUSAL_ORDER"
+	EnvMaxSkew  = "CHAT_MAX_SKEW"
+	EnvOrder    = "CHAT_ORDER"
+	EnvOutput   = "CHAT_OUTPUT"
+
+	EnvBotProfile = "CHAT_BOT_PROFILE"
+)
+
+// Config reúne as opções comuns ao cliente e ao bot.
+type Config struct {
+	Broker   string        `yaml:"broker"`
+	Proxy    string        `yaml:"proxy"`
+	Username string        `yaml:"username"`
+	Timeout  time.Duration `yaml:"timeout"`
+	Retries  int           `yaml:"retries"`
+	// Silêncio máximo na assinatura antes de reconectar (0 desativa)
+	IdleTimeout time.Duration `yaml:"idle_timeout"`
+	// Diretório dos arquivos de mensagens do cliente (vazio desativa)
+	StoreDir string `yaml:"store_dir"`
+	// Entrega causal das publicações com relógios vetoriais
+	CausalOrder bool `yaml:"causal_order"`
+	// Diferença para o relógio do servidor a partir da qual o cliente avisa
+	// (0 desativa)
+	MaxSkew time.Duration `yaml:"max_skew"`
+	// Ordem de exibição das mensagens recebidas: OrderArrival ou OrderHLC
+	Order string `yaml:"order"`
+	// Formato da saída do cliente fora da TUI: OutputText, OutputJSON ou
+	// OutputJSONL
+	Output string `yaml:"output"`
+
+	// Seção usada apenas pelo bot
+	Bot BotConfig `yaml:"bot"`
+
+	// Arquivo de onde a configuração foi lida (vazio se nenhum)
+	Path string `yaml:"-"`
+}
+
+// BotConfig escolhe o perfil de comportamento do bot e ajusta seus valores.
+// Campos ausentes (nil) mantêm os valores do perfil.
+type BotConfig struct {
+	Profile         string         `yaml:"profile"`
+	MinInterval     *time.Duration `yaml:"min_interval"`
+	MaxInterval     *time.Duration `yaml:"max_interval"`
+	Burst           *int           `yaml:"burst"`
+	Pause           *time.Duration `yaml:"pause"`
+	Hop             *float64       `yaml:"hop"`
+	DMRatio         *float64       `yaml:"dm_ratio"`
+	ReplyToMentions *bool          `yaml:"reply_to_mentions"`
+
+	// Arquivo com uma mensagem por linha (vazio usa as mensagens embutidas)
+	Corpus string `yaml:"corpus"`
+
+	// Limites da execução; zero executa para sempre
+	Messages int           `yaml:"messages"`
+	Duration time.Duration `yaml:"duration"`
+}
+
+// Ordens de exibição das mensagens recebidas
+const (
+	OrderArrival = "arrival"
+	// Segura as mensagens por um instante e exibe pelo relógio híbrido
+	OrderHLC = "hlc"
+)
+
+// Formatos da saída do cliente
+const (
+	OutputText = "text"
+	// Um objeto JSON indentado por resultado ou mensagem recebida
+	OutputJSON = "json"
+	// Um objeto JSON por linha
+	OutputJSONL = "jsonl"
+)
+
+// DefaultMaxSkew é a diferença de relógio tolerada antes do aviso.
+const DefaultMaxSkew = time.Second
+
+// Default retorna a configuração usada dentro do docker-compose.
+func Default() *Config {
+	return &Config{
+		Broker:  chatsdk.DefaultBrokerEndpoint,
+		Proxy:   chatsdk.DefaultProxyEndpoint,
+		Timeout: chatsdk.DefaultRetryPolicy.Timeout,
+		Retries: chatsdk.DefaultRetryPolicy.Attempts,
+
+		IdleTimeout: chatsdk.DefaultIdleTimeout,
+		StoreDir:    DefaultStoreDir(),
+		MaxSkew:     DefaultMaxSkew,
+		Order:       OrderArrival,
+		Output:      OutputText,
+	}
+}
+
+// DefaultPath é o arquivo lido quando nem --config nem CHAT_CONFIG são
+// informados. Se ele não existir, nenhum arquivo é usado.
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "chat-client", "config.yaml")
+}
+
+// DefaultStoreDir é onde o cliente guarda as mensagens recebidas, um arquivo
+// por usuário.
+func DefaultStoreDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "chat-client", "messages")
+}
+
+// Load registra as flags comuns em fs, faz o parse de args e aplica as fontes
+// de configuração na ordem de prioridade. Flags próprias do binário podem ser
+// registradas em fs antes da chamada.
+func Load(fs *flag.FlagSet, args []string) (*Config, error) {
+	path := fs.String("config", "", "arquivo de configuração YAML (env "+EnvConfig+")")
+	broker := fs.String("broker", "", "endereço do broker (env "+EnvBroker+", padrão "+chatsdk.DefaultBrokerEndpoint+")")
+	proxy := fs.String("proxy", "", "endereço do proxy (env "+EnvProxy+", padrão "+chatsdk.DefaultProxyEndpoint+")")
+	username := fs.String("user", "", "nome de usuário (env "+EnvUsername+")")
+	timeout := fs.Duration("timeout", 0, "espera por resposta em cada tentativa (env "+EnvTimeout+")")
+	retries := fs.Int("retries", 0, "tentativas por requisição (env "+EnvRetries+")")
+	idle := fs.Duration("idle-timeout", 0, "silêncio máximo na assinatura antes de reconectar, 0 desativa (env "+EnvIdle+")")
+	storeDir := fs.String("store-dir", "", "diretório das mensagens guardadas pelo cliente, vazio desativa (env "+EnvStoreDir+")")
+	maxSkew := fs.Duration("max-skew", 0, "diferença para o relógio do servidor que gera aviso, 0 desativa (env "+EnvMaxSkew+")")
+	order := fs.String("order", "", "ordem de exibição das mensagens: arrival ou hlc (env "+EnvOrder+")")
+	output := fs.String("output", "", "formato da saída: text, json ou jsonl (env "+EnvOutput+")")
+	causal := fs.Bool("causal", false, "entrega as publicações em ordem causal, com relógios vetoriais (env "+EnvCausal+")")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := Default()
+
+	// Arquivo
+	explicit := true
+	if *path == "" {
+		*path = os.Getenv(EnvConfig)
+	}
+	if *path == "" {
+		*path = DefaultPath()
+		explicit = false
+	}
+	if *path != "" {
+		err = cfg.loadFile(*path)
+		if errors.Is(err, os.ErrNotExist) && !explicit {
+			err = nil
+		} else if err == nil {
+			cfg.Path = *path
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Variáveis de ambiente
+	err = cfg.loadEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	// Flags (apenas as informadas explicitamente)
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "broker":
+			cfg.Broker = *broker
+		case "proxy":
+			cfg.Proxy = *proxy
+		case "user":
+			cfg.Username = *username
+		case "timeout":
+			cfg.Timeout = *timeout
+		case "retries":
+			cfg.Retries = *retries
+		case "idle-timeout":
+			cfg.IdleTimeout = *idle
+		case "store-dir":
+			cfg.StoreDir = *storeDir
+		case "causal":
+			cfg.CausalOrder = *causal
+		case "max-skew":
+			cfg.MaxSkew = *maxSkew
+		case "order":
+			cfg.Order = *order
+		case "output":
+			cfg.Output = *output
+		}
+	})
+
+	return cfg, cfg.validate()
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("erro ao ler configuração: %w", err)
+	}
+
+	err = yaml.Unmarshal(data, c)
+	if err != nil {
+		return fmt.Errorf("erro ao ler configuração %s: %v", path, err)
+	}
+	return nil
+}
+
+func (c *Config) loadEnv() error {
+	if v, ok := os.LookupEnv(EnvBroker); ok {
+		c.Broker = v
+	}
+	if v, ok := os.LookupEnv(EnvProxy); ok {
+		c.Proxy = v
+	}
+	if v, ok := os.LookupEnv(EnvUsername); ok {
+		c.Username = v
+	}
+	if v, ok := os.LookupEnv(EnvTimeout); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("%s inválido: %v", EnvTimeout, err)
+		}
+		c.Timeout = d
+	}
+	if v, ok := os.LookupEnv(EnvRetries); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%s inválido: %v", EnvRetries, err)
+		}
+		c.Retries = n
+	}
+	if v, ok := os.LookupEnv(EnvIdle); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("%s inválido: %v", EnvIdle, err)
+		}
+		c.IdleTimeout = d
+	}
+	if v, ok := os.LookupEnv(EnvStoreDir); ok {
+		c.StoreDir = v
+	}
+	if v, ok := os.LookupEnv(EnvCausal); ok {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("%s inválido: %v", EnvCausal, err)
+		}
+		c.CausalOrder = b
+	}
+	if v, ok := os.LookupEnv(EnvMaxSkew); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("%s inválido: %v", EnvMaxSkew, err)
+		}
+		c.MaxSkew = d
+	}
+	if v, ok := os.LookupEnv(EnvOrder); ok {
+		c.Order = v
+	}
+	if v, ok := os.LookupEnv(EnvOutput); ok {
+		c.Output = v
+	}
+	if v, ok := os.LookupEnv(EnvBotProfile); ok {
+		c.Bot.Profile = v
+	}
+	return nil
+}
+
+func (c *Config) validate() error {
+	if c.Broker == "" {
+		return fmt.Errorf("endereço do broker não configurado")
+	}
+	if c.Proxy == "" {
+		return fmt.Errorf("endereço do proxy não configurado")
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("timeout deve ser positivo: %v", c.Timeout)
+	}
+	if c.Retries < 1 {
+		return fmt.Errorf("retries deve ser pelo menos 1: %d", c.Retries)
+	}
+	if c.IdleTimeout < 0 {
+		return fmt.Errorf("idle_timeout não pode ser negativo: %v", c.IdleTimeout)
+	}
+	if c.MaxSkew < 0 {
+		return fmt.Errorf("max_skew não pode ser negativo: %v", c.MaxSkew)
+	}
+	if c.Order != OrderArrival && c.Order != OrderHLC {
+		return fmt.Errorf("ordem de exibição inválida: '%s' (use %s ou %s)", c.Order, OrderArrival, OrderHLC)
+	}
+	switch c.Output {
+	case OutputText, OutputJSON, OutputJSONL:
+	default:
+		return fmt.Errorf("formato de saída inválido: '%s' (use %s, %s ou %s)", c.Output, OutputText, OutputJSON, OutputJSONL)
+	}
+	return nil
+}
+
+// SDKOptions converte a configuração em opções do chatsdk.
+func (c *Config) SDKOptions() []chatsdk.Option {
+	options := []chatsdk.Option{
+		chatsdk.WithBroker(c.Broker),
+		chatsdk.WithProxy(c.Proxy),
+		chatsdk.WithUsername(c.Username),
+		chatsdk.WithRetryPolicy(chatsdk.RetryPolicy{
+			Timeout:  c.Timeout,
+			Attempts: c.Retries,
+		}),
+		chatsdk.WithIdleTimeout(c.IdleTimeout),
+	}
+	if c.CausalOrder {
+		options = append(options, chatsdk.WithCausalOrder(chatsdk.DefaultCausalWait))
+	}
+	return options
+}
@@ -0,0 +1,256 @@
+// Package store guarda em disco, em um arquivo bbolt por usuário, as
+// mensagens recebidas pelo cliente, para consulta posterior com Search, e a
+// fila de saída do chatsdk.Outbox.
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"chat-client/chatsdk"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// Mensagens recebidas, indexadas por timestamp
+	messagesBucket = []byte("messages")
+	// Itens da fila de saída (chatsdk.Outbox), indexados pela chave
+	outboxBucket = []byte("outbox")
+)
+
+// Entry é uma mensagem guardada. Channel é vazio nas mensagens privadas.
+type Entry struct {
+	Channel   string `json:"channel,omitempty"`
+	User      string `json:"user"`
+	Dst       string `json:"dst,omitempty"`
+	Message   string `json:"message"`
+	Timestamp int64  `json:"timestamp"`
+	Clock     int64  `json:"clock,omitempty"`
+}
+
+// Private indica se a entrada é uma mensagem privada.
+func (e Entry) Private() bool {
+	return e.Channel == ""
+}
+
+// Time converte o timestamp da mensagem.
+func (e Entry) Time() time.Time {
+	return time.UnixMilli(e.Timestamp)
+}
+
+// Query filtra a busca. Campos vazios não restringem o resultado.
+type Query struct {
+	// Trecho do texto, sem diferenciar maiúsculas
+	Text    string
+	Channel string
+	// Autor ou destinatário
+	User  string
+	Since time.Time
+	Until time.Time
+	// Máximo de resultados, os mais recentes (zero para todos)
+	Limit int
+}
+
+func (q Query) match(e Entry) bool {
+	if q.Channel != "" && e.Channel != q.Channel {
+		return false
+	}
+	if q.User != "" && e.User != q.User && e.Dst != q.User {
+		return false
+	}
+	return q.Text == "" || strings.Contains(strings.ToLower(e.Message), strings.ToLower(q.Text))
+}
+
+// Store é o arquivo de mensagens de um usuário. Pode ser usado por várias
+// goroutines.
+type Store struct {
+	db *bolt.DB
+}
+
+// Open abre (ou cria) o arquivo de mensagens de username em dir.
+func Open(dir, username string) (*Store, error) {
+	if username == "" || strings.ContainsAny(username, `/\`) || username == "." || username == ".." {
+		return nil, fmt.Errorf("nome de usuário inválido para o armazenamento: '%s'", username)
+	}
+
+	err := os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar diretório do armazenamento: %v", err)
+	}
+
+	path := filepath.Join(dir, username+".db")
+	// O timeout evita travar quando outro cliente do mesmo usuário está aberto
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao abrir armazenamento %s: %v", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{messagesBucket, outboxBucket} {
+			_, err := tx.CreateBucketIfNotExists(name)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("erro ao preparar armazenamento %s: %v", path, err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Close fecha o arquivo.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// AddPublication guarda uma publicação recebida.
+func (s *Store) AddPublication(p chatsdk.Publication) error {
+	return s.add(Entry{
+		Channel:   p.Channel,
+		User:      p.User,
+		Message:   p.Message,
+		Timestamp: p.Timestamp,
+		Clock:     p.Clock,
+	})
+}
+
+// AddPrivateMessage guarda uma mensagem privada, recebida ou enviada.
+func (s *Store) AddPrivateMessage(m chatsdk.PrivateMessage) error {
+	return s.add(Entry{
+		User:      m.Src,
+		Dst:       m.Dst,
+		Message:   m.Message,
+		Timestamp: m.Timestamp,
+		Clock:     m.Clock,
+	})
+}
+
+// A chave é o timestamp seguido de uma sequência, para manter as entradas em
+// ordem cronológica e permitir buscas por intervalo
+func entryKey(timestamp int64, seq uint64) []byte {
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key, uint64(max(timestamp, 0)))
+	binary.BigEndian.PutUint64(key[8:], seq)
+	return key
+}
+
+func (s *Store) add(e Entry) error {
+	value, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("erro ao serializar mensagem: %v", err)
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(messagesBucket)
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		return bucket.Put(entryKey(e.Timestamp, seq), value)
+	})
+	if err != nil {
+		return fmt.Errorf("erro ao gravar mensagem: %v", err)
+	}
+	return nil
+}
+
+// Search retorna as entradas que atendem a q em ordem cronológica. Com Limit,
+// ficam as mais recentes e truncated indica que havia mais.
+func (s *Store) Search(q Query) (entries []Entry, truncated bool, err error) {
+	var first []byte
+	if !q.Since.IsZero() {
+		first = entryKey(q.Since.UnixMilli(), 0)
+	}
+	var last []byte
+	if !q.Until.IsZero() {
+		last = entryKey(q.Until.UnixMilli()+1, 0)
+	}
+
+	err = s.db.View(func(tx *bolt.Tx) error {
+		// Percorre do fim para o começo, parando ao atingir o limite
+		cursor := tx.Bucket(messagesBucket).Cursor()
+		var key, value []byte
+		if last == nil {
+			key, value = cursor.Last()
+		} else {
+			key, value = cursor.Seek(last)
+			if key == nil {
+				key, value = cursor.Last()
+			} else {
+				key, value = cursor.Prev()
+			}
+		}
+
+		for ; key != nil; key, value = cursor.Prev() {
+			if first != nil && bytes.Compare(key, first) < 0 {
+				break
+			}
+			var e Entry
+			err := json.Unmarshal(value, &e)
+			if err != nil {
+				return fmt.Errorf("entrada corrompida: %v", err)
+			}
+			if !q.match(e) {
+				continue
+			}
+			if q.Limit > 0 && len(entries) == q.Limit {
+				truncated = true
+				break
+			}
+			entries = append(entries, e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("erro na busca: %v", err)
+	}
+
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, truncated, nil
+}
+
+// SaveOutbox grava (ou atualiza) um item da fila de saída. Junto com
+// DeleteOutbox e LoadOutbox, implementa chatsdk.OutboxStore.
+func (s *Store) SaveOutbox(item chatsdk.OutboxItem) error {
+	value, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("erro ao serializar item da fila de saída: %v", err)
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(outboxBucket).Put([]byte(item.Key), value)
+	})
+}
+
+func (s *Store) DeleteOutbox(key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(outboxBucket).Delete([]byte(key))
+	})
+}
+
+func (s *Store) LoadOutbox() ([]chatsdk.OutboxItem, error) {
+	var items []chatsdk.OutboxItem
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(outboxBucket).ForEach(func(key, value []byte) error {
+			var item chatsdk.OutboxItem
+			err := json.Unmarshal(value, &item)
+			if err != nil {
+				return fmt.Errorf("item '%s' da fila de saída corrompido: %v", key, err)
+			}
+			items = append(items, item)
+			return nil
+		})
+	})
+	return items, err
+}
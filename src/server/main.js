@@ -3,6 +3,11 @@ const msgpack = require('msgpack5')();
 const fs = require('fs').promises;
 const path = require('path');
 
+// Serviços cujas respostas são guardadas por request_id para responder a
+// reenvios sem repetir o efeito
+const MUTATING_SERVICES = new Set(['login', 'channel', 'publish', 'message']);
+const RESPONSE_CACHE_SIZE = 10000;
+
 class ChatServer {
     constructor() {
         this.repSocket = zmq.socket('rep');
@@ -19,6 +24,11 @@ class ChatServer {
         this.channels = new Set();
         this.messages = [];
         this.publications = [];
+        // request_id das publicações e mensagens já aceitas, persistidos
+        // junto com elas e replicados
+        this.requestIds = new Set();
+        // Respostas recentes dos serviços que alteram estado, por request_id
+        this.responseCache = new Map();
         
         // Relógio lógico e físico
         this.logicalClock = 0;
@@ -46,6 +56,7 @@ class ChatServer {
         this.registerWithReferenceServer();
         this.startHeartbeat();
         this.startAutoSave(); // Iniciar salvamento automático
+        this.startPubHeartbeat();
     }
 
     async loadData() {
@@ -82,6 +93,12 @@ class ChatServer {
         } catch (error) {
             console.log('Arquivo publications.json não encontrado, iniciando com dados vazios');
         }
+
+        for (const item of [...this.publications, ...this.messages]) {
+            if (item.request_id) {
+                this.requestIds.add(item.request_id);
+            }
+        }
     }
 
     async saveData() {
@@ -323,6 +340,27 @@ class ChatServer {
         }, 15000); // Heartbeat a cada 15 segundos
     }
     
+    startPubHeartbeat() {
+        // Publicar no proxy periodicamente para que os clientes percebam
+        // quando a assinatura parou de receber mensagens
+        setInterval(() => {
+            try {
+                const heartbeat = {
+                    service: 'heartbeat',
+                    data: {
+                        server: this.serverName,
+                        timestamp: Date.now(),
+                        clock: this.incrementClock()
+                    }
+                };
+
+                this.pubSocket.send(['_heartbeat', msgpack.encode(heartbeat)]);
+            } catch (error) {
+                console.error('Erro ao publicar heartbeat:', error);
+            }
+        }, 3000); // A cada 3 segundos
+    }
+    
     startAutoSave() {
         // Salvar dados automaticamente a cada 30 segundos
         setInterval(async () => {
@@ -357,6 +395,38 @@ class ChatServer {
             this.updateClock(data.clock);
         }
 
+        // Reenvio de uma requisição já respondida (a resposta se perdeu):
+        // repetir a resposta sem repetir o efeito
+        const requestId = data.request_id;
+        const cacheable = requestId && MUTATING_SERVICES.has(service);
+        if (cacheable && this.responseCache.has(requestId)) {
+            const cached = this.responseCache.get(requestId);
+            return {
+                service: cached.service,
+                data: {
+                    ...cached.data,
+                    timestamp: Date.now(),
+                    clock: this.incrementClock(),
+                    duplicate: true
+                }
+            };
+        }
+
+        const response = await this.dispatch(service, data);
+        if (requestId) {
+            response.data.request_id = requestId;
+        }
+        if (cacheable) {
+            this.responseCache.set(requestId, response);
+            if (this.responseCache.size > RESPONSE_CACHE_SIZE) {
+                // Map mantém a ordem de inserção: remover a mais antiga
+                this.responseCache.delete(this.responseCache.keys().next().value);
+            }
+        }
+        return response;
+    }
+
+    async dispatch(service, data) {
         switch (service) {
             case 'login':
                 return await this.handleLogin(data);
@@ -370,6 +440,10 @@ class ChatServer {
                 return await this.handlePublish(data);
             case 'message':
                 return await this.handleMessage(data);
+            case 'history':
+                return await this.handleHistory(data);
+            case 'dm_history':
+                return await this.handleDMHistory(data);
             case 'clock':
                 return await this.handleClock(data);
             case 'election':
@@ -508,8 +582,40 @@ class ChatServer {
         };
     }
 
+    // A publicação ou mensagem já foi aceita, por esta réplica ou por outra
+    // (via replicação), possivelmente antes de um reinício
+    isDuplicate(requestId) {
+        return Boolean(requestId) && this.requestIds.has(requestId);
+    }
+
+    rememberRequestId(requestId) {
+        if (requestId) {
+            this.requestIds.add(requestId);
+        }
+    }
+
+    // Resposta a um reenvio já aceito que não está no cache de respostas:
+    // sucesso, sem repetir o armazenamento nem a publicação
+    duplicateReply(service) {
+        return {
+            service,
+            data: {
+                status: 'OK',
+                timestamp: Date.now(),
+                clock: this.incrementClock(),
+                duplicate: true
+            }
+        };
+    }
+
     async handlePublish(data) {
-        const { user, channel, message, timestamp } = data;
+        // meta é opaco para o servidor: é guardado e repassado na publicação
+        const { user, channel, message, timestamp, meta, request_id } = data;
+
+        // Reenvio de uma publicação já aceita: confirmar sem repetir
+        if (this.isDuplicate(request_id)) {
+            return this.duplicateReply('publish');
+        }
         
         if (!this.channels.has(channel)) {
             return {
@@ -529,10 +635,13 @@ class ChatServer {
             channel,
             message,
             timestamp,
-            clock: this.incrementClock()
+            clock: this.incrementClock(),
+            ...(meta !== undefined && { meta }),
+            ...(request_id && { request_id })
         };
         
         this.publications.push(publication);
+        this.rememberRequestId(request_id);
         
         // Se somos primary, replicar para backups
         if (this.isPrimary) {
@@ -541,7 +650,9 @@ class ChatServer {
                 channel,
                 message,
                 timestamp,
-                clock: this.incrementClock()
+                clock: this.incrementClock(),
+                ...(meta !== undefined && { meta }),
+                ...(request_id && { request_id })
             });
         }
         
@@ -555,7 +666,8 @@ class ChatServer {
                 channel,
                 message,
                 timestamp,
-                clock: this.incrementClock()
+                clock: this.incrementClock(),
+                ...(meta !== undefined && { meta })
             }
         };
 
@@ -572,7 +684,13 @@ class ChatServer {
     }
 
     async handleMessage(data) {
-        const { src, dst, message, timestamp } = data;
+        // meta é opaco para o servidor: é guardado e repassado ao destinatário
+        const { src, dst, message, timestamp, meta, request_id } = data;
+
+        // Reenvio de uma mensagem já aceita: confirmar sem repetir
+        if (this.isDuplicate(request_id)) {
+            return this.duplicateReply('message');
+        }
         
         if (!this.users.has(dst)) {
             return {
@@ -592,10 +710,13 @@ class ChatServer {
             dst,
             message,
             timestamp,
-            clock: this.incrementClock()
+            clock: this.incrementClock(),
+            ...(meta !== undefined && { meta }),
+            ...(request_id && { request_id })
         };
         
         this.messages.push(msg);
+        this.rememberRequestId(request_id);
         
         // Se somos primary, replicar para backups
         if (this.isPrimary) {
@@ -604,7 +725,9 @@ class ChatServer {
                 dst,
                 message,
                 timestamp,
-                clock: this.incrementClock()
+                clock: this.incrementClock(),
+                ...(meta !== undefined && { meta }),
+                ...(request_id && { request_id })
             });
         }
         
@@ -618,7 +741,8 @@ class ChatServer {
                 dst,
                 message,
                 timestamp,
-                clock: this.incrementClock()
+                clock: this.incrementClock(),
+                ...(meta !== undefined && { meta })
             }
         };
 
@@ -634,6 +758,107 @@ class ChatServer {
         };
     }
     
+    // Seleciona uma página do histórico: os itens a partir de since e antes do
+    // cursor, limitados aos mais recentes. more indica se há itens mais
+    // antigos. Os itens são ordenados por (timestamp, request_id), iguais em
+    // todas as réplicas; o clock não serve de cursor, já que cada réplica
+    // atribui o seu. O cursor é (before, before_id): a página seguinte usa o
+    // timestamp e o request_id do item mais antigo recebido.
+    historyPage(items, { since, before, before_id, limit }) {
+        const max = limit > 0 ? Math.min(limit, 500) : 100;
+        const id = item => item.request_id ?? '';
+        const compare = (a, b) =>
+            (a.timestamp ?? 0) - (b.timestamp ?? 0) || (id(a) < id(b) ? -1 : id(a) > id(b) ? 1 : 0);
+        const cursor = { timestamp: before, request_id: before_id ?? '' };
+        const selected = items
+            .filter(item => (!since || item.timestamp >= since) && (!before || compare(item, cursor) < 0))
+            .sort(compare);
+        return {
+            page: selected.slice(-max),
+            more: selected.length > max
+        };
+    }
+
+    async handleHistory(data) {
+        const { channel } = data;
+        
+        if (!this.channels.has(channel)) {
+            return {
+                service: 'history',
+                data: {
+                    status: 'erro',
+                    timestamp: Date.now(),
+                    clock: this.incrementClock(),
+                    description: 'Canal não existe'
+                }
+            };
+        }
+
+        const { page, more } = this.historyPage(
+            this.publications.filter(p => p.channel === channel), data);
+        const publications = page.map(p => ({
+            user: p.user ?? '',
+            channel: p.channel,
+            message: p.message ?? '',
+            timestamp: p.timestamp ?? 0,
+            clock: p.clock ?? 0,
+            ...(p.meta !== undefined && { meta: p.meta }),
+            ...(p.request_id && { request_id: p.request_id })
+        }));
+
+        return {
+            service: 'history',
+            data: {
+                status: 'OK',
+                timestamp: Date.now(),
+                clock: this.incrementClock(),
+                publications,
+                more
+            }
+        };
+    }
+
+    async handleDMHistory(data) {
+        const { user, peer } = data;
+        
+        if (!this.users.has(user) || !this.users.has(peer)) {
+            return {
+                service: 'dm_history',
+                data: {
+                    status: 'erro',
+                    timestamp: Date.now(),
+                    clock: this.incrementClock(),
+                    description: 'Usuário não existe'
+                }
+            };
+        }
+
+        // Mensagens trocadas entre os dois, nos dois sentidos
+        const { page, more } = this.historyPage(
+            this.messages.filter(m =>
+                (m.src === user && m.dst === peer) || (m.src === peer && m.dst === user)), data);
+        const messages = page.map(m => ({
+            src: m.src,
+            dst: m.dst,
+            message: m.message ?? '',
+            timestamp: m.timestamp ?? 0,
+            clock: m.clock ?? 0,
+            ...(m.meta !== undefined && { meta: m.meta }),
+            ...(m.request_id && { request_id: m.request_id })
+        }));
+
+        return {
+            service: 'dm_history',
+            data: {
+                status: 'OK',
+                timestamp: Date.now(),
+                clock: this.incrementClock(),
+                messages,
+                more
+            }
+        };
+    }
+    
     async handleClock(data) {
         return {
             service: 'clock',
@@ -780,17 +1005,23 @@ class ChatServer {
     }
     
     async handleReplicatePublish(data) {
-        const { user, channel, message, timestamp } = data;
+        const { user, channel, message, timestamp, meta, request_id } = data;
+        if (this.isDuplicate(request_id)) {
+            return this.duplicateReply('replicate_publish');
+        }
         
         const publication = {
             user,
             channel,
             message,
             timestamp,
-            clock: this.incrementClock()
+            clock: this.incrementClock(),
+            ...(meta !== undefined && { meta }),
+            ...(request_id && { request_id })
         };
         
         this.publications.push(publication);
+        this.rememberRequestId(request_id);
         await this.saveData();
         
         return {
@@ -804,17 +1035,23 @@ class ChatServer {
     }
     
     async handleReplicateMessage(data) {
-        const { src, dst, message, timestamp } = data;
+        const { src, dst, message, timestamp, meta, request_id } = data;
+        if (this.isDuplicate(request_id)) {
+            return this.duplicateReply('replicate_message');
+        }
         
         const msg = {
             src,
             dst,
             message,
             timestamp,
-            clock: this.incrementClock()
+            clock: this.incrementClock(),
+            ...(meta !== undefined && { meta }),
+            ...(request_id && { request_id })
         };
         
         this.messages.push(msg);
+        this.rememberRequestId(request_id);
         await this.saveData();
         
         return {